@@ -0,0 +1,78 @@
+package admin
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/maxsupermanhd/FactoCord-3.0/v3/support"
+)
+
+var AuditCommandDoc = support.CommandDoc{
+	Name: "audit",
+	Usage: "$audit\n" +
+		"$audit <user|command>? <since>?",
+	Doc: "command searches the audit log of privileged commands, confirmations and console channel messages (`audit` in the config).\n" +
+		"A user can be a mention, an id or a username. " +
+		"`since` is a duration (`30m`, `12h`, `7d`) or a date (`2024-12-16`). The latest 20 entries are shown.",
+}
+
+// parseAuditSince parses a duration or a date
+func parseAuditSince(s string) (time.Time, bool) {
+	if d, err := support.ParseDuration(s); err == nil {
+		return time.Now().Add(-d), true
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, true
+	}
+	return time.Time{}, false
+}
+
+func AuditCommand(s *discordgo.Session, _ *discordgo.Message, args string) {
+	if support.Config.Audit.File == "" {
+		support.Send(s, "The audit log is disabled")
+		return
+	}
+	var filter string
+	var since time.Time
+	for _, arg := range strings.Fields(args) {
+		if t, ok := parseAuditSince(arg); ok && since.IsZero() {
+			since = t
+		} else if filter == "" {
+			filter = strings.TrimPrefix(arg, support.Config.Prefix)
+		} else {
+			support.SendFormat(s, "Usage: "+AuditCommandDoc.Usage)
+			return
+		}
+	}
+	if id, ok := support.ParseUserMention(filter); ok {
+		filter = id
+	}
+
+	entries, err := support.ReadAudit(func(entry *support.AuditEntryT) bool {
+		if entry.Time.Before(since) {
+			return false
+		}
+		return filter == "" || entry.UserID == filter || strings.EqualFold(entry.Username, filter) ||
+			strings.EqualFold(entry.Command, filter)
+	})
+	if err != nil {
+		support.Panik(err, "... when reading the audit log")
+		support.Send(s, "Error reading the audit log")
+		return
+	}
+	if len(entries) == 0 {
+		support.Send(s, "No entries found")
+		return
+	}
+	list := support.DefaultTextList("**Audit log:**")
+	for i := len(entries) - 1; i >= 0 && list.Len() < 20; i-- {
+		list.Append(entries[i].Format())
+	}
+	if len(entries) > list.Len() {
+		list.Heading = "**Audit log (latest " + strconv.Itoa(list.Len()) + " of " + strconv.Itoa(len(entries)) + "):**"
+	}
+	support.ChunkedMessageSend(s, list.Render())
+}
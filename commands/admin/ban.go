@@ -15,7 +15,7 @@ var BanPlayerDoc = support.CommandDoc{
 }
 
 // BanPlayer bans a player on the server.
-func BanPlayer(s *discordgo.Session, args string) {
+func BanPlayer(s *discordgo.Session, _ *discordgo.Message, args string) {
 	if len(args) == 0 {
 		support.SendFormat(s, "Usage: "+BanPlayerDoc.Usage)
 		return
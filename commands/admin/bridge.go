@@ -0,0 +1,135 @@
+package admin
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/maxsupermanhd/FactoCord-3.0/v3/support"
+)
+
+var BridgeCommandDoc = support.CommandDoc{
+	Name:  "bridge",
+	Usage: "$bridge mute <user|player> <duration>?\n$bridge unmute <user|player>\n$bridge mutes",
+	Doc:   "command stops the messages of a discord user or a player from crossing the bridge without kicking or banning them",
+	Subcommands: []support.CommandDoc{
+		{
+			Name:  "mute",
+			Usage: "$bridge mute <user|player> <duration>?",
+			Doc: "command mutes a discord user (a mention or an id) or a player (a name). " +
+				"`duration` is `30m`, `12h` or `7d`, without it the mute lasts until `$bridge unmute`. " +
+				"The muted user is told about it once",
+		},
+		{
+			Name:  "unmute",
+			Usage: "$bridge unmute <user|player>",
+			Doc:   "command lets the messages of the user cross the bridge again",
+		},
+		{
+			Name:  "mutes",
+			Usage: "$bridge mutes",
+			Doc:   "command lists muted users and players",
+		},
+	},
+}
+
+// parseMuteDuration parses `30m`, `12h` or `7d`
+func parseMuteDuration(s string) (time.Duration, bool) {
+	d, err := support.ParseDuration(s)
+	return d, err == nil && d > 0
+}
+
+// muteTarget resolves a mention or an id to a discord user, anything else is a player.
+// Without mustExist a user who isn't in the guild anymore is found by the id
+func muteTarget(s *discordgo.Session, target string, mustExist bool) (kind, id, name string, ok bool) {
+	id, isUser := support.ParseUserMention(target)
+	if !isUser {
+		return support.MutePlayer, target, target, true
+	}
+	member, err := s.GuildMember(support.GuildID, id)
+	if err != nil {
+		if !mustExist {
+			return support.MuteDiscord, id, id, true
+		}
+		return "", "", "", false
+	}
+	return support.MuteDiscord, id, member.User.Username, true
+}
+
+func BridgeCommand(s *discordgo.Session, m *discordgo.Message, args string) {
+	action, args := support.SplitDivide(strings.TrimSpace(args), " ")
+	fields := strings.Fields(args)
+	switch action {
+	case "mute":
+		if len(fields) < 1 || len(fields) > 2 {
+			support.SendFormat(s, "Usage: "+BridgeCommandDoc.Subcommands[0].Usage)
+			return
+		}
+		kind, id, name, ok := muteTarget(s, fields[0], true)
+		if !ok {
+			support.Send(s, "User not found in the guild")
+			return
+		}
+		mute := support.BridgeMuteT{Kind: kind, ID: id, Name: name, By: m.Author.Username}
+		if len(fields) == 2 {
+			d, ok := parseMuteDuration(fields[1])
+			if !ok {
+				support.Send(s, "Invalid duration \""+fields[1]+"\", use e.g. `30m`, `12h` or `7d`")
+				return
+			}
+			mute.Until = time.Now().Add(d)
+		}
+		err := support.MuteBridge(mute)
+		if err != nil {
+			support.Panik(err, "... when writing "+support.Config.BridgeMutesFile)
+			support.Send(s, "Error saving the mute")
+			return
+		}
+		support.SendComplex(s, &discordgo.MessageSend{
+			Content:         fmt.Sprintf("Messages of %s **%s** won't cross the bridge %s", kind, name, mute.Describe()),
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
+		})
+	case "unmute":
+		if len(fields) != 1 {
+			support.SendFormat(s, "Usage: "+BridgeCommandDoc.Subcommands[1].Usage)
+			return
+		}
+		kind, id, name, ok := muteTarget(s, fields[0], false)
+		if !ok {
+			support.Send(s, "User not found in the guild")
+			return
+		}
+		unmuted, err := support.UnmuteBridge(kind, id)
+		if err != nil {
+			support.Panik(err, "... when writing "+support.Config.BridgeMutesFile)
+			support.Send(s, "Error saving the mutes")
+			return
+		}
+		if !unmuted {
+			support.SendComplex(s, &discordgo.MessageSend{
+				Content:         fmt.Sprintf("The %s **%s** is not muted", kind, name),
+				AllowedMentions: &discordgo.MessageAllowedMentions{},
+			})
+			return
+		}
+		support.SendComplex(s, &discordgo.MessageSend{
+			Content:         fmt.Sprintf("Messages of %s **%s** cross the bridge again", kind, name),
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
+		})
+	case "mutes":
+		mutes := support.BridgeMutes()
+		if len(mutes) == 0 {
+			support.Send(s, "Nobody is muted")
+			return
+		}
+		list := support.DefaultTextList("**Muted on the bridge:**")
+		for _, mute := range mutes {
+			list.Append(fmt.Sprintf("%s **%s** %s (by %s)", mute.Kind, mute.Name, mute.Describe(), mute.By))
+		}
+		support.ChunkedMessageSend(s, list.Render())
+	default:
+		support.SendFormat(s, "Usage: "+BridgeCommandDoc.Usage)
+	}
+}
@@ -68,8 +68,13 @@ var ConfigCommandDoc = support.CommandDoc{
 	},
 }
 
+// ConfigCommandConfirm asks for confirmation of $config load because it discards unsaved changes
+func ConfigCommandConfirm(args string) bool {
+	return strings.TrimSpace(args) == "load"
+}
+
 // ModCommand returns the list of mods running on the server.
-func ConfigCommand(s *discordgo.Session, args string) {
+func ConfigCommand(s *discordgo.Session, _ *discordgo.Message, args string) {
 	if args == "" {
 		support.SendFormat(s, "Usage: "+ConfigCommandDoc.Usage)
 		return
@@ -108,6 +113,10 @@ func save(args string) string {
 	return "Config saved"
 }
 
+// ConfigReloaded is called after $config load and after $config set changes custom_commands,
+// e.g. to register custom commands again
+var ConfigReloaded func()
+
 func load(args string) string {
 	if args != "" {
 		return "Load accepts no arguments"
@@ -116,6 +125,9 @@ func load(args string) string {
 	if err != nil {
 		return err.Error()
 	}
+	if ConfigReloaded != nil {
+		ConfigReloaded()
+	}
 	return "Config reloaded"
 }
 
@@ -229,6 +241,12 @@ func set(args string) string {
 	default:
 		return fmt.Sprintf("%s's type (%s) is not supported", pathS, current.Type().String())
 	}
+	if err := support.Config.Validate(); err != nil {
+		return "Value set, but the config is invalid: " + err.Error()
+	}
+	if path[0] == "custom_commands" && ConfigReloaded != nil {
+		ConfigReloaded()
+	}
 	return "Value set"
 }
 
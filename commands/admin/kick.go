@@ -17,7 +17,7 @@ var KickPlayerDoc = support.CommandDoc{
 }
 
 // KickPlayer kicks a player from the server.
-func KickPlayer(s *discordgo.Session, args string) {
+func KickPlayer(s *discordgo.Session, _ *discordgo.Message, args string) {
 	if len(args) == 0 {
 		support.SendFormat(s, "Usage: "+KickPlayerDoc.Usage)
 		return
@@ -11,6 +11,7 @@ import (
 	"os"
 	"path"
 	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/bwmarrin/discordgo"
@@ -70,6 +71,21 @@ func (m *ModJSON) removeMod(modname string) (removed bool) {
 	return false
 }
 
+// ModListNames returns names of mods in mod-list.json
+func ModListNames() []string {
+	mods := &ModJSON{}
+	err := support.ReadJSON(support.Config.ModListLocation, mods)
+	if err != nil {
+		support.Panik(err, "there was an error reading mod list")
+		return nil
+	}
+	var names []string
+	for _, mod := range mods.Mods {
+		names = append(names, mod.Name)
+	}
+	return names
+}
+
 type modDescriptionT struct {
 	name    string
 	path    string
@@ -136,24 +152,32 @@ type modRelease struct {
 	FileName    string `json:"file_name"`
 	Name        string
 	Version     string
-	InfoJson    struct {
-		Dependencies    []string
-		FactorioVersion string `json:"factorio_version"`
-	} `json:"info_json"`
+	InfoJson    modInfoJsonT `json:"info_json"`
+	Owner       string       `json:"-"`
+	size        int64        // cached by releaseSize, 0 - not requested yet
+}
+
+// modInfoJsonT is a part of mod's info.json
+type modInfoJsonT struct {
+	Dependencies    []string
+	FactorioVersion string `json:"factorio_version"`
 }
 
 type modPortalResponse struct {
 	Message  string
 	Name     string
+	Owner    string
 	Releases []modRelease
 }
 
 var ModCommandDoc = support.CommandDoc{
 	Name:  "mod",
-	Usage: "$mod (add|remove|enable|disable) <modnames>+ | update <modnames>*",
+	Usage: "$mod (add|remove|enable|disable) --dry-run? <modnames>+ | update --dry-run? <modnames>* | info <modname> | graph | verify <modnames>* | prune | trash | undo | history | requests | approve <id> | reject <id> | queue | cancel <files>+ | cache (stats|prune)",
 	Doc: "command downloads, removes, enables and disables several mods.\n" +
 		"If mod's name contains a whitespace ' ', it's name should be quoted using double quotes (e.g. `\"Squeak Through\"`).\n" +
-		"All subcommands can process several mods at once. Mods' names should be separated by a whitespace.",
+		"All subcommands can process several mods at once. Mods' names should be separated by a whitespace.\n" +
+		"`add`, `update`, `remove`, `enable` and `disable` accept `--dry-run`: the command shows what would be downloaded (with sizes), " +
+		"deleted and changed in mod-list.json without changing anything.",
 	Subcommands: []support.CommandDoc{
 		{
 			Name:  "add",
@@ -161,7 +185,8 @@ var ModCommandDoc = support.CommandDoc{
 			Doc: "command adds mods to mod-list.json and downloads the latest version or a specified version.\n" +
 				"To download the latest version of a mod type a mod name.\n" +
 				"To specify a version for a mod add '==' and a version (e.g. `$mod add FNEI==0.3.4`).\n" +
-				"This command ensures that factorio version is the same as mod's factorio version.",
+				"This command ensures that factorio version is the same as mod's factorio version.\n" +
+				"Built-in mods (`space-age`, `quality`, `elevated-rails`) are only added to mod-list.json.",
 		},
 		{
 			Name: "update",
@@ -188,11 +213,120 @@ var ModCommandDoc = support.CommandDoc{
 			Usage: "$mod disable <modname>+",
 			Doc:   "command disables mods in mod-list.json",
 		},
+		{
+			Name:  "info",
+			Usage: "$mod info <modname>",
+			Doc: "command shows the installed version of a mod, its dependencies and installed mods that depend on it.\n" +
+				"Dependencies are marked as required, optional or incompatible. " +
+				"If the mod isn't downloaded, dependencies of its latest release on the mod portal are shown.",
+		},
+		{
+			Name: "graph",
+			Doc: "command sends a dependency graph of all enabled mods as a Graphviz DOT file.\n" +
+				"Optional dependencies are dashed, incompatibilities and missing dependencies are red.",
+		},
+		{
+			Name: "verify",
+			Usage: "$mod verify\n" +
+				"$mod verify <modname>+\n" +
+				"$mod verify --redownload <modname>*",
+			Doc: "command checks installed mod files against the sha1 reported by the mod portal.\n" +
+				"It reports damaged files, local builds unknown to the mod portal and mods from mod-list.json without files.\n" +
+				"With `--redownload` damaged files are downloaded again.",
+		},
+		{
+			Name: "prune",
+			Usage: "$mod prune\n" +
+				"$mod prune confirm\n" +
+				"$mod prune delete",
+			Doc: "command lists mod files that are not in mod-list.json and older versions of mods that have a newer version downloaded.\n" +
+				"Versions specified in mod-list.json are kept.\n" +
+				"`$mod prune confirm` moves the files to the trash (`mod_trash_dir` in the config), `$mod prune delete` deletes them (`$mod undo` can still restore them).",
+		},
+		{
+			Name: "trash",
+			Usage: "$mod trash\n" +
+				"$mod trash restore <file>+\n" +
+				"$mod trash empty",
+			Doc: "command lists mod files in the trash, restores them into the mods directory or deletes them permanently",
+		},
+		{
+			Name: "undo",
+			Doc: "command reverts the last change made by `$mod`: restores mod-list.json, deletes downloaded files and restores deleted files.\n" +
+				"Every change is recorded in the mod journal (`mod_journal` in the config), deleted files are kept there.\n" +
+				"Running it again reverts the change before that.",
+		},
+		{
+			Name:  "history",
+			Usage: "$mod history <count>?",
+			Doc:   "command lists recent changes made by `$mod` and who made them",
+		},
+		{
+			Name: "requests",
+			Doc: "command lists mod requests waiting for admin approval.\n" +
+				"Users that are not admins are restricted by `mod_policy` in the config: denied mods, allowed mods and authors and the maximum total size of mods. " +
+				"If `mod_policy.require_approval` is set, mods that are not allowed create a request instead of being refused.",
+		},
+		{
+			Name:  "approve",
+			Usage: "$mod approve <id>",
+			Doc:   "command runs the requested command ignoring `mod_policy` and notifies the user who made the request. Only admins can use it",
+		},
+		{
+			Name:  "reject",
+			Usage: "$mod reject <id> <reason>?",
+			Doc:   "command deletes a mod request and notifies the user who made it. Only admins can use it",
+		},
+		{
+			Name: "queue",
+			Doc: "command shows mods that are being downloaded or are waiting in the download queue.\n" +
+				"Several mods are downloaded in parallel, failed downloads are retried (see `mod_downloads` in the config).",
+		},
+		{
+			Name:  "cancel",
+			Usage: "$mod cancel <file>+",
+			Doc: "command cancels downloads of the specified mods.\n" +
+				"A download can be specified either by its filename (e.g. `FNEI_0.3.4.zip`) or by the mod name.",
+		},
+		{
+			Name: "cache",
+			Usage: "$mod cache stats\n" +
+				"$mod cache prune\n" +
+				"$mod cache prune <days>\n" +
+				"$mod cache prune all",
+			Doc: "command manages the local cache of downloaded mods (`mod_cache` in the config).\n" +
+				"Mods found in the cache are not downloaded from the mod portal again.\n" +
+				"`$mod cache stats` shows the size of the cache.\n" +
+				"`$mod cache prune` deletes archives that weren't used for `mod_cache.max_age` days (0 - no age limit) or for the specified number of days. " +
+				"`$mod cache prune all` deletes all archives.",
+		},
 	},
 }
 
+// ModCommandConfirm asks for confirmation of $mod remove unless it's a dry run and of $mod prune delete
+func ModCommandConfirm(args string) bool {
+	action, rest := support.SplitDivide(strings.TrimSpace(args), " ")
+	switch action {
+	case "remove":
+		return !strings.Contains(" "+rest+" ", " --dry-run ")
+	case "prune":
+		return strings.TrimSpace(rest) == "delete"
+	}
+	return false
+}
+
+// ModCommandOutbound tells if the command requests the mod portal
+func ModCommandOutbound(args string) bool {
+	action, _ := support.SplitDivide(strings.TrimSpace(args), " ")
+	switch action {
+	case "add", "update", "info", "verify", "approve":
+		return true
+	}
+	return false
+}
+
 // ModCommand returns the list of mods running on the server.
-func ModCommand(s *discordgo.Session, args string) {
+func ModCommand(s *discordgo.Session, m *discordgo.Message, args string) {
 	argsList := strings.SplitN(args, " ", 2)
 	if len(argsList) == 0 {
 		support.SendFormat(s, "Usage: "+ModCommandDoc.Usage)
@@ -200,12 +334,57 @@ func ModCommand(s *discordgo.Session, args string) {
 	}
 
 	action := argsList[0]
+	admin := m != nil && m.Author != nil && support.IsAdmin(m.Author.ID)
+	var approved *modRequestT
 	switch action {
-	case "update":
+	case "update", "verify", "prune", "graph", "undo":
 		//
-	case "add", "remove", "enable", "disable":
+	case "requests":
+		support.ChunkedMessageSend(s, modsRequests())
+		return
+	case "approve", "reject":
+		if !admin {
+			support.Send(s, "Only admins can approve mod requests")
+			return
+		}
+		if len(argsList) < 2 {
+			support.SendFormat(s, "Usage: $mod "+action+" <id>")
+			return
+		}
+		if action == "reject" {
+			support.Send(s, modsReject(strings.Fields(argsList[1])))
+			return
+		}
+		var errs string
+		approved, errs = findModRequest(strings.TrimSpace(argsList[1]))
+		if approved == nil {
+			support.Send(s, errs)
+			return
+		}
+		// run the requested command as if an admin typed it
+		action = approved.Action
+		args = approved.Action + " " + strings.Join(approved.Mods, " ")
+		argsList = strings.SplitN(args, " ", 2)
+	case "history":
+		support.ChunkedMessageSend(s, modsHistory(strings.Fields(strings.Join(argsList[1:], " "))))
+		return
+	case "queue":
+		support.ChunkedMessageSend(s, downloads.Render())
+		return
+	case "cache":
+		support.Send(s, modsCache(strings.Fields(strings.Join(argsList[1:], " "))))
+		return
+	case "trash":
+		args, mismatched := support.QuoteSplit(strings.Join(argsList[1:], " "), "\"")
+		if mismatched {
+			support.Send(s, "Error: Mismatched quotes")
+			return
+		}
+		support.ChunkedMessageSend(s, modsTrash(args))
+		return
+	case "add", "remove", "enable", "disable", "cancel", "info":
 		if len(argsList) < 2 {
-			support.SendFormat(s, "Usage: $mod "+action+" <modname> [<modname>]+")
+			support.SendFormat(s, "Usage: "+modSubcommandUsage(action))
 			return
 		}
 	default:
@@ -218,6 +397,15 @@ func ModCommand(s *discordgo.Session, args string) {
 		support.Send(s, "Error: Mismatched quotes")
 		return
 	}
+	dryRun := false
+	switch action {
+	case "add", "update", "remove", "enable", "disable":
+		modnames, dryRun = parseDryRun(modnames)
+		if len(modnames) == 0 && action != "update" {
+			support.SendFormat(s, "Usage: $mod "+action+" --dry-run? <modname> [<modname>]+")
+			return
+		}
+	}
 	var modDescriptions []modDescriptionT
 	if action == "add" || action == "update" {
 		for _, modname := range modnames {
@@ -242,6 +430,13 @@ func ModCommand(s *discordgo.Session, args string) {
 		support.Send(s, "Who am I supposed to change a single mod twice?")
 		return
 	}
+	if action == "cancel" {
+		support.ChunkedMessageSend(s, modsCancel(modnames))
+		return
+	}
+
+	modListMutex.Lock()
+	defer modListMutex.Unlock()
 
 	modsListFile, err := os.ReadFile(support.Config.ModListLocation)
 	if err != nil {
@@ -258,39 +453,89 @@ func ModCommand(s *discordgo.Session, args string) {
 		return
 	}
 
+	if action == "verify" {
+		support.SetTyping(s)
+		support.ChunkedMessageSend(s, modsVerify(s, mods, modnames))
+		return
+	}
+	if action == "info" {
+		support.ChunkedMessageSend(s, modsInfo(mods, modnames))
+		return
+	}
+	if action == "graph" {
+		sendModsGraph(s, mods)
+		return
+	}
+
+	if action == "undo" {
+		if len(modnames) != 0 {
+			support.Send(s, "Undo accepts no arguments")
+			return
+		}
+		res, ok := modsUndo(mods)
+		if ok {
+			err = support.WriteJSON(support.Config.ModListLocation, mods)
+			if err != nil {
+				support.Send(s, "Sorry, there was an error saving mod list")
+				support.Panik(err, "there was an error saving mod list")
+				return
+			}
+		}
+		support.ChunkedMessageSend(s, res)
+		return
+	}
+
+	// every change is a transaction: if something fails nothing is saved and removed files are restored
+	trx := newModTransaction(m, "mod "+args, mods)
+	trx.dryRun = dryRun
+	policy := newModPolicy(admin, matchModsWithFiles(&mods.Mods))
 	var res string
+	ok := true
 	switch action {
 	case "add":
 		support.SetTyping(s)
-		res = modsAdd(s, mods, &modDescriptions)
+		res, ok = modsAdd(mods, &modDescriptions, trx, policy)
 	case "update":
 		support.SetTyping(s)
-		res = modsUpdate(s, mods, &modDescriptions)
+		res, ok = modsUpdate(mods, &modDescriptions, trx, policy)
 	case "remove":
-		res = modsRemove(mods, modnames)
+		res = modsRemove(mods, modnames, trx)
 	case "enable":
 		res = modsEnable(mods, modnames, true)
 	case "disable":
 		res = modsEnable(mods, modnames, false)
+	case "prune":
+		res = modsPrune(mods, modnames, trx)
 	}
-
-	modsListFile, err = json.MarshalIndent(mods, "", "    ")
-	if err != nil {
-		support.Send(s, "Sorry, there was an error converting mod list to json")
-		support.Panik(err, "there was an error converting mod list to json")
+	if !ok {
+		support.ChunkedMessageSend(s, res+trx.rollback())
 		return
 	}
-	err = os.WriteFile(support.Config.ModListLocation, modsListFile, 0666)
+	if dryRun {
+		support.ChunkedMessageSend(s, res+modsDryRun(trx, mods, policy))
+		return
+	}
+
+	err = support.WriteJSON(support.Config.ModListLocation, mods)
 	if err != nil {
-		support.Send(s, "Sorry, there was an error saving mod list")
+		support.Send(s, "Sorry, there was an error saving mod list"+trx.rollback())
 		support.Panik(err, "there was an error saving mod list")
 		return
 	}
+	trx.commit(s, mods)
+	res += policy.submit(trx, action)
 
+	if approved != nil {
+		_, errs := takeModRequest(strconv.Itoa(approved.ID))
+		if errs != "" {
+			res += "\n" + errs
+		}
+		res = fmt.Sprintf("<@%s> your mod request #%d was approved\n", approved.UserID, approved.ID) + res
+	}
 	support.ChunkedMessageSend(s, res)
 }
 
-func modsAdd(s *discordgo.Session, mods *ModJSON, modDescriptions *[]modDescriptionT) string {
+func modsAdd(mods *ModJSON, modDescriptions *[]modDescriptionT, trx *modTransactionT, policy *modPolicyT) (string, bool) {
 	var toDownload []*modRelease
 
 	files := matchModsWithFiles(&mods.Mods)
@@ -299,9 +544,9 @@ func modsAdd(s *discordgo.Session, mods *ModJSON, modDescriptions *[]modDescript
 	alreadyAdded := support.DefaultTextList("\n**Already added:**")
 	userErrors := support.DefaultTextList("\n**Errors:**")
 
-	factorioVersion, err := getFactorioVersionNoPatch()
+	factorioVersion, err := getFactorioVersion()
 	if err != nil {
-		return "Error checking factorio version"
+		return "Error checking factorio version", false
 	}
 
 	for _, desc := range *modDescriptions {
@@ -309,19 +554,43 @@ func modsAdd(s *discordgo.Session, mods *ModJSON, modDescriptions *[]modDescript
 			alreadyAdded.Append(desc.String())
 			continue
 		}
-		release, userError, err := checkModPortal(&desc, factorioVersion)
+		if isBuiltinMod(desc.name) {
+			if !builtinModAvailable(desc.name, factorioVersion) {
+				userErrors.Append(fmt.Sprintf("%s: built-in mod is not available in factorio %s", desc.name, factorioVersion))
+			} else if mods.sortedInsert(&Mod{Name: desc.name, Enabled: true}) {
+				addedMods.Append(desc.name + " (built-in)")
+			} else {
+				alreadyAdded.Append(desc.name)
+				alreadyAdded.AddToLast(support.FormatUsage(" - built-in mods can be enabled with `$mod enable`"))
+			}
+			continue
+		}
+		release, userError, err := checkModPortal(&desc, factorioVersion.major)
 		if err != nil {
-			return "Some connection error occurred"
+			support.Panik(err, "... when requesting the mod portal")
+			return connectionError(err), false
 		}
 		if userError != "" {
 			userErrors.Append(fmt.Sprintf("%s: %s", desc.String(), userError))
 			continue
 		}
+		verdict, reason := policy.check(release)
+		if verdict == policyApproval {
+			policy.requireApproval(&desc, reason)
+			continue
+		}
+		if verdict == policyDenied {
+			userErrors.Append(fmt.Sprintf("%s: %s", desc.String(), reason))
+			continue
+		}
+		if reason := policy.reserve(release, 0); reason != "" {
+			userErrors.Append(fmt.Sprintf("%s: %s", desc.String(), reason))
+			continue
+		}
 
 		toDownload = append(toDownload, release)
 		inserted := mods.sortedInsert(desc.ModEntry())
 		if inserted {
-			release.Name = desc.name
 			addedMods.Append(desc.String())
 		} else {
 			alreadyAdded.Append(desc.String())
@@ -337,6 +606,8 @@ func modsAdd(s *discordgo.Session, mods *ModJSON, modDescriptions *[]modDescript
 			res = fmt.Sprintf("Mod \"%s\" is already added", (*modDescriptions)[0].String())
 		} else if userErrors.NotEmpty() {
 			res = strings.TrimSpace(userErrors.List[0])
+		} else if len(policy.pending) != 0 {
+			res = fmt.Sprintf("Mod \"%s\" wasn't added", (*modDescriptions)[0].String())
 		} else {
 			res = fmt.Sprintf("Added mod \"%s\"", (*modDescriptions)[0].String())
 		}
@@ -345,37 +616,23 @@ func modsAdd(s *discordgo.Session, mods *ModJSON, modDescriptions *[]modDescript
 		res += alreadyAdded.RenderNotEmpty()
 		res += userErrors.RenderNotEmpty()
 	}
-	res += checkDependencies(toDownload, files)
+	res += checkDependencies(toDownload, files, mods, factorioVersion)
 
 	if support.Config.ModPortalToken == "" {
 		res += "\n**No token to download mods**"
 	} else if support.Config.Username == "" {
 		res += "\n**No username to download mods**"
 	} else {
-		if !modDownloaderStarted {
-			go modDownloader(s)
-		}
-		for _, x := range toDownload {
-			downloadQueue <- x
-		}
+		trx.download(toDownload...)
 	}
-	return res
+	return res, true
 }
 
-func getFactorioVersionNoPatch() (string, error) {
-	factorioVersion, err := support.FactorioVersion()
-	if err != nil {
-		return "", err
-	}
-	factorioVersion = strings.Join(strings.Split(factorioVersion, ".")[:2], ".")
-	return factorioVersion, nil
-}
-
-func modsUpdate(s *discordgo.Session, mods *ModJSON, modDescriptions *[]modDescriptionT) string {
+func modsUpdate(mods *ModJSON, modDescriptions *[]modDescriptionT, trx *modTransactionT, policy *modPolicyT) (string, bool) {
 	if support.Config.ModPortalToken == "" {
-		return "**No token to download mods**"
+		return "**No token to download mods**", false
 	} else if support.Config.Username == "" {
-		return "**No username to download mods**"
+		return "**No username to download mods**", false
 	}
 
 	updatedMods := support.DefaultTextList("**Updating mods:**")
@@ -386,9 +643,9 @@ func modsUpdate(s *discordgo.Session, mods *ModJSON, modDescriptions *[]modDescr
 
 	files := matchModsWithFiles(&mods.Mods)
 
-	factorioVersion, err := getFactorioVersionNoPatch()
+	factorioVersion, err := getFactorioVersion()
 	if err != nil {
-		return "Error checking factorio version"
+		return "Error checking factorio version", false
 	}
 
 	updateAll := true
@@ -396,16 +653,21 @@ func modsUpdate(s *discordgo.Session, mods *ModJSON, modDescriptions *[]modDescr
 		updateAll = false
 		*modDescriptions = nil
 		for _, mod := range mods.Mods {
-			if mod.Name != "base" {
+			if !isBuiltinMod(mod.Name) {
 				*modDescriptions = append(*modDescriptions, modDescriptionT{name: mod.Name})
 			}
 		}
 	}
 
 	for _, desc := range *modDescriptions {
-		release, userError, err := checkModPortal(&desc, factorioVersion)
+		if isBuiltinMod(desc.name) {
+			userErrors.Append(fmt.Sprintf("%s: built-in mods are updated together with factorio", desc.name))
+			continue
+		}
+		release, userError, err := checkModPortal(&desc, factorioVersion.major)
 		if err != nil {
-			return "Some connection error occurred"
+			support.Panik(err, "... when requesting the mod portal")
+			return connectionError(err), false
 		}
 		if userError != "" {
 			userErrors.Append(fmt.Sprintf("%s: %s", desc.String(), userError))
@@ -427,6 +689,25 @@ func modsUpdate(s *discordgo.Session, mods *ModJSON, modDescriptions *[]modDescr
 			alreadyUpdated.Append(desc.String())
 			continue
 		}
+		verdict, reason := policy.check(release)
+		if verdict == policyApproval {
+			policy.requireApproval(&desc, reason)
+			continue
+		}
+		if verdict == policyDenied {
+			userErrors.Append(fmt.Sprintf("%s: %s", desc.String(), reason))
+			continue
+		}
+		var freed int64
+		for _, version := range versions {
+			if info, err := os.Stat(version.path); err == nil {
+				freed += info.Size()
+			}
+		}
+		if reason := policy.reserve(release, freed); reason != "" {
+			userErrors.Append(fmt.Sprintf("%s: %s", desc.String(), reason))
+			continue
+		}
 		releaseVersion := support.SemanticVersionPanic(release.Version)
 		toDownload = append(toDownload, release)
 		updatedMods.Append(fmt.Sprintf(
@@ -436,61 +717,68 @@ func modsUpdate(s *discordgo.Session, mods *ModJSON, modDescriptions *[]modDescr
 			versionsArrow(versionsVersions, releaseVersion),
 			release.Version,
 		))
-		_, err = removeModFiles(files, desc.name)
+		_, err = removeModFiles(files, desc.name, trx)
 		if err != nil {
 			updatedMods.AddToLast(": error removing files")
 		}
 	}
-	if !modDownloaderStarted {
-		go modDownloader(s)
-	}
-	for _, x := range toDownload {
-		downloadQueue <- x
-	}
+	trx.download(toDownload...)
 
-	dependencies := checkDependencies(toDownload, files)
+	dependencies := checkDependencies(toDownload, files, mods, factorioVersion)
 	if updateAll {
-		return updatedMods.Render() + alreadyUpdated.RenderNotEmpty() + userErrors.RenderNotEmpty() + dependencies
+		return updatedMods.Render() + alreadyUpdated.RenderNotEmpty() + userErrors.RenderNotEmpty() + dependencies, true
 	} else {
-		return updatedMods.Render() + userErrors.RenderNotEmpty() + dependencies
+		return updatedMods.Render() + userErrors.RenderNotEmpty() + dependencies, true
 	}
 }
 
-func modsRemove(mods *ModJSON, modnames []string) string {
+func modsRemove(mods *ModJSON, modnames []string, trx *modTransactionT) string {
 	removedMods := support.DefaultTextList("**Removed %d mods (left: %d):**")
 	notFound := support.DefaultTextList("\n**%d mods weren't found:**")
 	removedFiles := support.DefaultTextList("\n**Files removed:**")
+	failed := support.DefaultTextList("\n**%d mods were kept, there was an error removing their files. Try shutting down the server:**")
 
 	files := matchModsWithFiles(&mods.Mods)
+	keptAside := ""
 
 	for _, modname := range modnames {
+		if isBuiltinMod(modname) {
+			notFound.Append(modname + support.FormatUsage(" (built-in mods can't be removed, use `$mod disable`)"))
+			continue
+		}
+		// if a file can't be removed the mod is kept as it was
+		before := append([]Mod{}, mods.Mods...)
+		removedBefore := len(trx.Removed)
 		found := mods.removeMod(modname)
+		filesFound, err := removeModFiles(files, modname, trx)
+		if err != nil {
+			mods.Mods = before
+			failed.Append(modname)
+			if notRestored := trx.restoreFrom(removedBefore); len(notRestored) > 0 {
+				keptAside += trx.keptAsideMessage(notRestored)
+				failed.AddToLast(trx.keptAsideMessage(notRestored))
+			}
+			continue
+		}
 		if found {
 			removedMods.Append(modname)
 		}
-
-		if removedFiles.Error == "" {
-			filesFound, err := removeModFiles(files, modname)
-			if err != nil {
-				removedFiles.Error = "\nThere was an error removing mod files. Try shutting down the server"
-				continue
-			}
-			found = found || len(filesFound) > 0
-			for _, desc := range filesFound {
-				removedFiles.Append(desc.String())
-			}
+		found = found || len(filesFound) > 0
+		for _, desc := range filesFound {
+			removedFiles.Append(desc.String())
 		}
 		if !found {
 			notFound.Append(modname)
 		}
 	}
 	if len(modnames) == 1 {
-		if notFound.NotEmpty() {
+		if isBuiltinMod(modnames[0]) {
+			return support.FormatUsage("Built-in mods can't be removed, use `$mod disable`")
+		} else if failed.NotEmpty() {
+			return "There was an error removing mod files, the mod was kept. Try shutting down the server" + keptAside
+		} else if notFound.NotEmpty() {
 			return "Mod \"" + modnames[0] + "\" not found"
 		} else if removedFiles.NotEmpty() {
-			if removedFiles.Error != "" {
-				return removedFiles.Error
-			}
 			return "Removed " + removedFiles.List[0]
 		} else {
 			return "Removed mod \"" + modnames[0] + "\""
@@ -498,7 +786,8 @@ func modsRemove(mods *ModJSON, modnames []string) string {
 	} else {
 		removedMods.Heading = fmt.Sprintf(removedMods.Heading, removedMods.Len(), len(mods.Mods))
 		notFound.FormatHeaderWithLength()
-		return removedMods.Render() + removedFiles.RenderNotEmpty() + notFound.RenderNotEmpty()
+		failed.FormatHeaderWithLength()
+		return removedMods.Render() + removedFiles.RenderNotEmpty() + notFound.RenderNotEmpty() + failed.RenderNotEmpty()
 	}
 }
 
@@ -538,10 +827,35 @@ func modsEnable(mods *ModJSON, modnames []string, setEnabled bool) string {
 	}
 }
 
+func modsCancel(names []string) string {
+	cancelled := support.DefaultTextList("**Cancelled %d downloads:**")
+	notFound := support.DefaultTextList("\n**%d downloads weren't found:**")
+	for _, name := range names {
+		files := downloads.Cancel(name)
+		if len(files) == 0 {
+			notFound.Append(name)
+		}
+		for _, file := range files {
+			cancelled.Append(file)
+		}
+	}
+	if len(names) == 1 && cancelled.Len() <= 1 {
+		if notFound.NotEmpty() {
+			return "Download \"" + names[0] + "\" not found"
+		}
+		return "Cancelled download of " + cancelled.List[0]
+	}
+	cancelled.FormatHeaderWithLength()
+	notFound.FormatHeaderWithLength()
+	return cancelled.Render() + notFound.RenderNotEmpty()
+}
+
 func matchModsWithFiles(mods *[]Mod) *modsFilesT {
 	res := modsFiles()
 	for _, mod := range *mods {
-		res.missing[mod.Name] = true
+		if !isBuiltinMod(mod.Name) {
+			res.missing[mod.Name] = true
+		}
 	}
 	baseDir := path.Dir(support.Config.ModListLocation)
 	files, err := os.ReadDir(baseDir)
@@ -581,13 +895,13 @@ func matchModsWithFiles(mods *[]Mod) *modsFilesT {
 	return res
 }
 
-func removeModFiles(files *modsFilesT, modname string) (found []modDescriptionT, err error) {
+func removeModFiles(files *modsFilesT, modname string, trx *modTransactionT) (found []modDescriptionT, err error) {
 	modFiles, ok := files.versions[modname]
 	if !ok {
 		return nil, nil
 	}
 	for _, desc := range modFiles {
-		err := os.Remove(desc.path)
+		err := trx.removeFile(desc.path)
 		if err != nil {
 			return modFiles, err
 		}
@@ -595,25 +909,56 @@ func removeModFiles(files *modsFilesT, modname string) (found []modDescriptionT,
 	return modFiles, nil
 }
 
-func checkModPortal(desc *modDescriptionT, factorioVersion string) (*modRelease, string, error) {
-	resp, err := http.Get(fmt.Sprintf("https://mods.factorio.com/api/mods/%s/full", desc.name))
+// modSubcommandUsage returns the usage from the doc of the $mod subcommand
+func modSubcommandUsage(name string) string {
+	for _, subcommand := range ModCommandDoc.Subcommands {
+		if subcommand.Name == name && subcommand.Usage != "" {
+			return subcommand.Usage
+		}
+	}
+	return "$mod " + name + " <modname> [<modname>]+"
+}
+
+// connectionError describes a failed request to the user
+func connectionError(err error) string {
+	if errors.Is(err, support.ErrHTTPBudget) {
+		return "Too many requests to factorio.com, try again in a minute"
+	}
+	return "Some connection error occurred"
+}
+
+func fetchModPortal(name string) (*modPortalResponse, error) {
+	resp, err := http.Get(fmt.Sprintf("https://mods.factorio.com/api/mods/%s/full", name))
 	if err != nil {
-		return nil, "", err
+		return nil, err
 	}
 	body, err := io.ReadAll(resp.Body)
 	resp.Body.Close()
 	if err != nil {
-		return nil, "", err
+		return nil, err
 	}
 
 	response := modPortalResponse{}
 	err = json.Unmarshal(body, &response)
+	if err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+func checkModPortal(desc *modDescriptionT, factorioVersion string) (*modRelease, string, error) {
+	response, err := fetchModPortal(desc.name)
 	if err != nil {
 		return nil, "", err
 	}
 	if response.Message == "Mod not found" {
 		return nil, "mod not found on the mod portal", nil
 	}
+	// portal releases don't have a name, the policy and downloads need it
+	for i := range response.Releases {
+		response.Releases[i].Name = desc.name
+		response.Releases[i].Owner = response.Owner
+	}
 
 	if desc.version.Full == "" { // no version specified
 		for z := len(response.Releases) - 1; z >= 0; z-- {
@@ -621,6 +966,13 @@ func checkModPortal(desc *modDescriptionT, factorioVersion string) (*modRelease,
 				return &response.Releases[z], "", nil
 			}
 		}
+		if len(response.Releases) != 0 {
+			return nil, fmt.Sprintf(
+				"no release for factorio %s (the latest release is for factorio %s)",
+				factorioVersion,
+				response.Releases[len(response.Releases)-1].InfoJson.FactorioVersion,
+			), nil
+		}
 		return nil, "no release for this factorio version", nil
 	} else {
 		for _, release := range response.Releases {
@@ -640,9 +992,9 @@ func checkModPortal(desc *modDescriptionT, factorioVersion string) (*modRelease,
 	}
 }
 
-var dependencyRegexp = regexp.MustCompile(`^(!|\?|\(\?\))? ?([A-Za-z0-9\-_ ]+)(?: ([<>]?=?) (\d+\.\d+(?:\.\d+)?))?$`)
+var dependencyRegexp = regexp.MustCompile(`^(!|\?|\(\?\)|~)? ?([A-Za-z0-9\-_ ]+)(?: ([<>]?=?) (\d+\.\d+(?:\.\d+)?))?$`)
 
-func checkDependencies(newMods []*modRelease, files *modsFilesT) string {
+func checkDependencies(newMods []*modRelease, files *modsFilesT, mods *ModJSON, factorioVersion *factorioVersionT) string {
 	installed := map[string][]*support.SemanticVersionT{}
 	for _, mod := range newMods {
 		installed[mod.Name] = append(installed[mod.Name], support.SemanticVersionPanic(mod.Version))
@@ -653,6 +1005,17 @@ func checkDependencies(newMods []*modRelease, files *modsFilesT) string {
 		}
 	}
 
+	// built-in mods are enabled unless they are disabled in mod-list.json
+	enabled := map[string]bool{}
+	for _, mod := range builtinMods {
+		enabled[mod.name] = builtinModAvailable(mod.name, factorioVersion)
+	}
+	for _, mod := range mods.Mods {
+		if isBuiltinMod(mod.Name) {
+			enabled[mod.Name] = mod.Enabled
+		}
+	}
+
 	missingModsList := support.DefaultTextList("\n**Missing dependencies:**")
 	incompatibleModsList := support.DefaultTextList("\n**Incompatible Mods:**")
 	wrongVersionMods := support.DefaultTextList("\n**Wrong version is installed:**")
@@ -670,7 +1033,10 @@ func checkDependencies(newMods []*modRelease, files *modsFilesT) string {
 			name := strings.TrimSpace(match[2])
 			compare := match[3]
 			depVersion := match[4]
-			if name == "base" {
+			if isBuiltinMod(name) {
+				if problem := checkBuiltinDependency(mod, prefix, name, compare, depVersion, enabled, factorioVersion); problem != "" {
+					incompatibleModsList.Append(problem)
+				}
 				continue
 			}
 			if prefix == "?" || prefix == "(?)" {
@@ -739,92 +1105,6 @@ func checkDependencies(newMods []*modRelease, files *modsFilesT) string {
 	return res
 }
 
-func compareFactorioVersions(modVersion, factorioVersion string) bool {
-	if modVersion == "0.18" {
-		return factorioVersion == "0.18" || factorioVersion == "1.0"
-	}
-	return modVersion == factorioVersion
-}
-
-var downloadQueue = make(chan *modRelease, 100)
-var modDownloaderStarted = false
-
-func modDownloader(s *discordgo.Session) {
-	modDownloaderStarted = true
-	baseDir := path.Dir(support.Config.ModListLocation)
-	for {
-		mod := <-downloadQueue
-
-		file, err := os.OpenFile(
-			path.Join(baseDir, mod.FileName),
-			os.O_CREATE|os.O_TRUNC|os.O_RDWR,
-			0664,
-		)
-		if err != nil {
-			support.Panik(err, "Error opening "+mod.FileName+" for write")
-			support.Send(s, mod.FileName+": error opening file for write")
-		}
-
-		url := fmt.Sprintf(
-			"https://mods.factorio.com%s?username=%s&token=%s",
-			mod.DownloadUrl,
-			support.Config.Username,
-			support.Config.ModPortalToken,
-		)
-		resp, err := http.Get(url)
-		if err != nil {
-			support.Panik(err, "Error downloading mod")
-			support.Send(s, mod.FileName+": Error downloading mod")
-			continue
-		}
-		if resp.ContentLength < 0 {
-			if strings.Contains(resp.Request.URL.Path, "login") {
-				support.Send(s, "Error logging in to download mods. Check username and mod portal token")
-			} else {
-				support.Panik(errors.New("content length error"), "Error downloading mod")
-				support.Send(s, "Error downloading mod")
-				continue
-			}
-		}
-
-		message := support.Send(s, support.FormatNamed(support.Config.Messages.DownloadStart, "file", mod.FileName))
-		counter := &support.WriteCounter{Total: uint64(resp.ContentLength)}
-		progress := support.ProgressUpdate{
-			WriteCounter: counter,
-			Message:      message,
-			Progress:     support.FormatNamed(support.Config.Messages.DownloadProgress, "file", mod.FileName),
-			Finished:     support.FormatNamed(support.Config.Messages.DownloadComplete, "file", mod.FileName),
-		}
-		go support.DownloadProgressUpdater(s, &progress)
-
-		_, err = io.Copy(io.MultiWriter(file, counter), resp.Body)
-		resp.Body.Close()
-		if err != nil {
-			counter.Error = true
-			support.Panik(err, "Error downloading mod file")
-			continue
-		}
-
-		if mod.SHA1 != "" {
-			_, err = file.Seek(0, 0) // to the start
-			if err != nil {
-				panic(err)
-			}
-
-			hash, err := fileHash(file)
-			if err != nil {
-				support.Panik(err, "... calculating sha1")
-				continue
-			}
-			if mod.SHA1 != hash {
-				counter.Error = true
-				message.Edit(s, fmt.Sprintf(":interrobang: %s is downloaded but hashsum is invalid", mod.FileName))
-			}
-		}
-		file.Close()
-	}
-}
-
 func fileHash(file io.Reader) (string, error) {
 	hash := sha1.New()
 	_, err := io.Copy(hash, file)
@@ -0,0 +1,242 @@
+package admin
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/maxsupermanhd/FactoCord-3.0/v3/support"
+)
+
+// Mod cache stores downloaded archives as <dir>/<sha1>/<filename>,
+// so the same release is never downloaded twice, even by different FactoCord instances
+
+func modCacheEnabled() bool {
+	return support.Config.ModCache.Dir != ""
+}
+
+func modCachePath(mod *modRelease) string {
+	return filepath.Join(support.Config.ModCache.Dir, mod.SHA1, mod.FileName)
+}
+
+// restoreFromModCache copies the release from the cache into the mods directory.
+// It returns false if the release is not cached or the cached file is damaged
+func restoreFromModCache(mod *modRelease) bool {
+	if !modCacheEnabled() || mod.SHA1 == "" {
+		return false
+	}
+	cached := modCachePath(mod)
+	if !support.FileExists(cached) {
+		return false
+	}
+	baseDir := path.Dir(support.Config.ModListLocation)
+	tmpPath, hash, err := copyToTemp(cached, baseDir)
+	if err != nil {
+		support.Panik(err, "... when copying "+mod.FileName+" from the mod cache")
+		return false
+	}
+	if hash != mod.SHA1 {
+		_ = os.Remove(tmpPath)
+		_ = os.RemoveAll(filepath.Dir(cached))
+		return false
+	}
+	err = os.Rename(tmpPath, path.Join(baseDir, mod.FileName))
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		support.Panik(err, "... when moving "+mod.FileName+" from the mod cache")
+		return false
+	}
+	now := time.Now()
+	_ = os.Chtimes(cached, now, now) // mtime is used as the last time the archive was used
+	return true
+}
+
+// storeInModCache copies a downloaded and verified release into the cache
+func storeInModCache(mod *modRelease, file string) {
+	if !modCacheEnabled() || mod.SHA1 == "" {
+		return
+	}
+	cached := modCachePath(mod)
+	if support.FileExists(cached) {
+		return
+	}
+	dir := filepath.Dir(cached)
+	err := os.MkdirAll(dir, 0775)
+	if err != nil {
+		support.Panik(err, "... when creating the mod cache directory")
+		return
+	}
+	tmpPath, _, err := copyToTemp(file, dir)
+	if err != nil {
+		support.Panik(err, "... when copying "+mod.FileName+" to the mod cache")
+		return
+	}
+	err = os.Rename(tmpPath, cached)
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		support.Panik(err, "... when moving "+mod.FileName+" to the mod cache")
+	}
+}
+
+// copyToTemp copies src into a temporary file in dir and returns its path and sha1
+func copyToTemp(src, dir string) (string, string, error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return "", "", err
+	}
+	defer in.Close()
+	out, err := os.CreateTemp(dir, ".download-*")
+	if err != nil {
+		return "", "", err
+	}
+	hash, err := fileHash(io.TeeReader(in, out))
+	closeErr := out.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(out.Name())
+		return "", "", err
+	}
+	return out.Name(), hash, nil
+}
+
+type modCacheEntryT struct {
+	dir      string
+	file     string
+	size     int64
+	lastUsed time.Time
+}
+
+func modCacheEntries() ([]modCacheEntryT, error) {
+	var res []modCacheEntryT
+	dirs, err := os.ReadDir(support.Config.ModCache.Dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, dir := range dirs {
+		if !dir.IsDir() {
+			continue
+		}
+		dirPath := filepath.Join(support.Config.ModCache.Dir, dir.Name())
+		files, err := os.ReadDir(dirPath)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			info, err := file.Info()
+			if err != nil || !info.Mode().IsRegular() || file.Name()[0] == '.' {
+				continue
+			}
+			res = append(res, modCacheEntryT{
+				dir:      dirPath,
+				file:     file.Name(),
+				size:     info.Size(),
+				lastUsed: info.ModTime(),
+			})
+		}
+	}
+	return res, nil
+}
+
+func modsCache(args []string) string {
+	if !modCacheEnabled() {
+		return "Mod cache is disabled"
+	}
+	usage := support.FormatUsage("Usage: $mod cache stats | prune <days|all>?")
+	if len(args) == 0 {
+		return usage
+	}
+	entries, err := modCacheEntries()
+	if err != nil {
+		support.Panik(err, "... when reading the mod cache")
+		return "Error reading the mod cache"
+	}
+	switch args[0] {
+	case "stats":
+		if len(args) != 1 {
+			return usage
+		}
+		return modCacheStats(entries)
+	case "prune":
+		maxAge := support.Config.ModCache.MaxAge
+		if len(args) == 2 {
+			if args[1] == "all" {
+				return modCachePrune(entries, 0, true)
+			}
+			maxAge, err = strconv.Atoi(args[1])
+			if err != nil || maxAge <= 0 {
+				return usage
+			}
+		} else if len(args) > 2 {
+			return usage
+		} else if maxAge == 0 {
+			return support.FormatUsage("`mod_cache.max_age` is 0, there is no age limit. " +
+				"Specify the days with `$mod cache prune <days>` or delete everything with `$mod cache prune all`")
+		}
+		return modCachePrune(entries, maxAge, false)
+	default:
+		return usage
+	}
+}
+
+func modCacheStats(entries []modCacheEntryT) string {
+	if len(entries) == 0 {
+		return fmt.Sprintf("Mod cache `%s` is empty", support.Config.ModCache.Dir)
+	}
+	var total, stale int64
+	staleCount := 0
+	cutoff := time.Now().AddDate(0, 0, -support.Config.ModCache.MaxAge)
+	oldest := entries[0]
+	for _, entry := range entries {
+		total += entry.size
+		if entry.lastUsed.Before(cutoff) {
+			stale += entry.size
+			staleCount++
+		}
+		if entry.lastUsed.Before(oldest.lastUsed) {
+			oldest = entry
+		}
+	}
+	res := fmt.Sprintf("**Mod cache** `%s`: %d archive%s, %s",
+		support.Config.ModCache.Dir, len(entries), support.PluralS(len(entries)), support.FormatSize(total))
+	res += fmt.Sprintf("\nLeast recently used: %s (%s)", oldest.file, oldest.lastUsed.Format("2006.01.02"))
+	if staleCount > 0 && support.Config.ModCache.MaxAge != 0 {
+		res += support.FormatUsage(fmt.Sprintf(
+			"\n%d archive%s (%s) weren't used for more than %d days, use `$mod cache prune` to delete them",
+			staleCount, support.PluralS(staleCount), support.FormatSize(stale), support.Config.ModCache.MaxAge,
+		))
+	}
+	return res
+}
+
+// modCachePrune deletes archives that weren't used for maxAge days, or every archive with all
+func modCachePrune(entries []modCacheEntryT, maxAge int, all bool) string {
+	cutoff := time.Now().AddDate(0, 0, -maxAge)
+	var freed int64
+	count := 0
+	for _, entry := range entries {
+		if !all && !entry.lastUsed.Before(cutoff) {
+			continue
+		}
+		err := os.RemoveAll(entry.dir)
+		if err != nil {
+			support.Panik(err, "... when pruning the mod cache")
+			return "Error deleting " + entry.file
+		}
+		freed += entry.size
+		count++
+	}
+	if count == 0 {
+		return "Nothing to prune"
+	}
+	return fmt.Sprintf("Deleted %d archive%s from the mod cache, freed %s",
+		count, support.PluralS(count), support.FormatSize(freed))
+}
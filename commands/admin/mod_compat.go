@@ -0,0 +1,121 @@
+package admin
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/maxsupermanhd/FactoCord-3.0/v3/support"
+)
+
+// factorioVersionT is the version of the factorio server mods are checked against
+type factorioVersionT struct {
+	full  support.SemanticVersionT // e.g. 2.0.28
+	major string                   // e.g. 2.0, compared with mods' factorio_version
+}
+
+func (v *factorioVersionT) String() string {
+	return v.full.Full
+}
+
+func getFactorioVersion() (*factorioVersionT, error) {
+	version, err := support.FactorioVersion()
+	if err != nil {
+		return nil, err
+	}
+	full, verr := support.SemanticVersion(version)
+	if verr != nil {
+		return nil, *verr
+	}
+	return &factorioVersionT{
+		full:  *full,
+		major: strings.Join(strings.Split(version, ".")[:2], "."),
+	}, nil
+}
+
+// modCompatibility lists factorio_version values of the mods that a factorio release can load.
+// Factorio 1.0 still loads mods made for 0.18, every other release (1.1 → 2.0 included)
+// requires mods to be updated. Releases missing from this table load only their own mods
+var modCompatibility = map[string][]string{
+	"0.17": {"0.17"},
+	"0.18": {"0.18"},
+	"1.0":  {"1.0", "0.18"},
+	"1.1":  {"1.1"},
+	"2.0":  {"2.0"},
+}
+
+func compareFactorioVersions(modVersion, factorioVersion string) bool {
+	compatible, ok := modCompatibility[factorioVersion]
+	if !ok {
+		return modVersion == factorioVersion
+	}
+	for _, version := range compatible {
+		if version == modVersion {
+			return true
+		}
+	}
+	return false
+}
+
+// builtinMods are shipped with factorio (since the specified version) and can't be downloaded from the mod portal.
+// They can still be listed in mod-list.json to be enabled or disabled
+var builtinMods = []struct {
+	name  string
+	since string
+}{
+	{"base", "0.0"},
+	{"elevated-rails", "2.0"},
+	{"quality", "2.0"},
+	{"space-age", "2.0"},
+}
+
+func isBuiltinMod(name string) bool {
+	for _, mod := range builtinMods {
+		if mod.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// builtinModAvailable checks if a built-in mod is shipped with the specified factorio version
+func builtinModAvailable(name string, factorioVersion *factorioVersionT) bool {
+	for _, mod := range builtinMods {
+		if mod.name == name {
+			return factorioVersion.full.Compare(support.SemanticVersionPanic(mod.since)) >= 0
+		}
+	}
+	return false
+}
+
+// checkBuiltinDependency checks a dependency of mod on a built-in mod.
+// It returns an empty string if the dependency is satisfied
+func checkBuiltinDependency(
+	mod *modRelease, prefix, name, compare, depVersion string,
+	enabled map[string]bool, factorioVersion *factorioVersionT,
+) string {
+	if prefix == "?" || prefix == "(?)" {
+		return ""
+	}
+	if prefix == "!" {
+		if enabled[name] {
+			return fmt.Sprintf("%s is incompatible with %s", mod.Name, name)
+		}
+		return ""
+	}
+	if !builtinModAvailable(name, factorioVersion) {
+		return fmt.Sprintf("%s requires %s which is not available in factorio %s", mod.Name, name, factorioVersion)
+	}
+	if name != "base" && !enabled[name] {
+		return support.FormatUsage(fmt.Sprintf(
+			"%s requires built-in mod %s, enable it with `$mod enable %s`", mod.Name, name, name,
+		))
+	}
+	// all built-in mods have the same version as factorio
+	if compare != "" && !support.CompareOp(factorioVersion.full.Compare(support.SemanticVersionPanic(depVersion)), compare) {
+		return fmt.Sprintf(
+			"%s requires %s %s %s but the server is running factorio %s",
+			mod.Name, name, compare, depVersion, factorioVersion,
+		)
+	}
+	return ""
+}
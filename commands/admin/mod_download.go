@@ -0,0 +1,317 @@
+package admin
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/maxsupermanhd/FactoCord-3.0/v3/support"
+)
+
+const (
+	downloadQueued = iota
+	downloadRunning
+	downloadRetrying
+)
+
+type downloadJobT struct {
+	release *modRelease
+	state   int
+	attempt int
+	counter *support.WriteCounter
+	ctx     context.Context
+	cancel  context.CancelFunc
+}
+
+// downloadManagerT downloads mods from the mod portal using several workers
+type downloadManagerT struct {
+	sync.Mutex
+	cond    *sync.Cond
+	jobs    []*downloadJobT // queued and running jobs in the order they were added
+	workers int
+}
+
+var downloads = newDownloadManager()
+
+// errDownloadLogin is returned when the mod portal redirects to the login page
+var errDownloadLogin = errors.New("error logging in to download mods. Check username and mod portal token")
+
+func newDownloadManager() *downloadManagerT {
+	d := &downloadManagerT{}
+	d.cond = sync.NewCond(&d.Mutex)
+	return d
+}
+
+// Enqueue adds releases to the download queue and starts the workers if needed.
+// Releases which are already in the queue are skipped.
+func (d *downloadManagerT) Enqueue(s *discordgo.Session, releases ...*modRelease) {
+	d.Lock()
+	defer d.Unlock()
+	for _, release := range releases {
+		if d.find(release.FileName) != nil {
+			continue
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		d.jobs = append(d.jobs, &downloadJobT{
+			release: release,
+			state:   downloadQueued,
+			ctx:     ctx,
+			cancel:  cancel,
+		})
+	}
+	for d.workers == 0 || d.workers < support.Config.ModDownloads.Workers {
+		d.workers++
+		go d.worker(s)
+	}
+	d.cond.Broadcast()
+}
+
+// Cancel cancels queued or running downloads whose filename or mod name matches name
+func (d *downloadManagerT) Cancel(name string) (cancelled []string) {
+	d.Lock()
+	defer d.Unlock()
+	jobs := d.jobs[:0]
+	for _, job := range d.jobs {
+		if job.release.FileName != name && job.release.Name != name {
+			jobs = append(jobs, job)
+			continue
+		}
+		job.cancel()
+		cancelled = append(cancelled, job.release.FileName)
+		if job.state != downloadQueued {
+			jobs = append(jobs, job) // the worker removes it when it notices the cancellation
+		}
+	}
+	d.jobs = jobs
+	return
+}
+
+// Render returns the state of the download queue
+func (d *downloadManagerT) Render() string {
+	d.Lock()
+	defer d.Unlock()
+	if len(d.jobs) == 0 {
+		return "Download queue is empty"
+	}
+	list := support.DefaultTextList(fmt.Sprintf("**Download queue (%d):**", len(d.jobs)))
+	for _, job := range d.jobs {
+		status := ""
+		switch job.state {
+		case downloadQueued:
+			status = "queued"
+		case downloadRunning:
+			status = "downloading"
+			if job.counter != nil && job.counter.Total > 0 {
+				status += fmt.Sprintf(" %2.1f%%", job.counter.Percent())
+			}
+		case downloadRetrying:
+			status = "waiting to retry"
+		}
+		if job.attempt > 1 {
+			status += fmt.Sprintf(" (attempt %d/%d)", job.attempt, support.Config.ModDownloads.Retries+1)
+		}
+		if job.ctx.Err() != nil {
+			status = "cancelling"
+		}
+		list.Append(fmt.Sprintf("%s - %s", job.release.FileName, status))
+	}
+	return list.Render()
+}
+
+func (d *downloadManagerT) find(filename string) *downloadJobT {
+	for _, job := range d.jobs {
+		if job.release.FileName == filename {
+			return job
+		}
+	}
+	return nil
+}
+
+func (d *downloadManagerT) worker(s *discordgo.Session) {
+	for {
+		d.Lock()
+		var job *downloadJobT
+		for job == nil {
+			for _, x := range d.jobs {
+				if x.state == downloadQueued {
+					job = x
+					break
+				}
+			}
+			if job == nil {
+				d.cond.Wait()
+			}
+		}
+		job.state = downloadRunning
+		d.Unlock()
+
+		d.download(s, job)
+
+		d.Lock()
+		for i, x := range d.jobs {
+			if x == job {
+				d.jobs = append(d.jobs[:i], d.jobs[i+1:]...)
+				break
+			}
+		}
+		d.Unlock()
+		job.cancel()
+	}
+}
+
+func (d *downloadManagerT) setState(job *downloadJobT, state int) {
+	d.Lock()
+	job.state = state
+	d.Unlock()
+}
+
+// download downloads a single job retrying with an exponential backoff
+func (d *downloadManagerT) download(s *discordgo.Session, job *downloadJobT) {
+	mod := job.release
+	if restoreFromModCache(mod) {
+		support.SendMessage(s, support.FormatNamed(support.Config.Messages.DownloadCached, "file", mod.FileName))
+		return
+	}
+	message := support.Send(s, support.FormatNamed(support.Config.Messages.DownloadStart, "file", mod.FileName))
+	delay := time.Duration(support.Config.ModDownloads.RetryDelay) * time.Second
+	for attempt := 0; ; attempt++ {
+		d.Lock()
+		job.attempt = attempt + 1
+		job.state = downloadRunning
+		d.Unlock()
+
+		err := d.downloadFile(s, job, message)
+		if err == nil {
+			return
+		}
+		if job.ctx.Err() != nil {
+			message.Edit(s, fmt.Sprintf(":x: Download of %s was cancelled", mod.FileName))
+			return
+		}
+		support.Panik(err, "Error downloading "+mod.FileName)
+		if err == errDownloadLogin || attempt >= support.Config.ModDownloads.Retries {
+			message.Edit(s, fmt.Sprintf(":interrobang: %s: %s", mod.FileName, err))
+			return
+		}
+		message.Edit(s, fmt.Sprintf(
+			":arrows_counterclockwise: %s: %s, retrying in %s",
+			mod.FileName, err, delay.String(),
+		))
+		d.setState(job, downloadRetrying)
+		select {
+		case <-time.After(delay):
+		case <-job.ctx.Done():
+			message.Edit(s, fmt.Sprintf(":x: Download of %s was cancelled", mod.FileName))
+			return
+		}
+		delay *= 2
+	}
+}
+
+// downloadFile downloads the mod to a temporary file and moves it into the mods directory
+// only after the hashsum is checked
+func (d *downloadManagerT) downloadFile(s *discordgo.Session, job *downloadJobT, message *support.MessageControlT) error {
+	mod := job.release
+	baseDir := path.Dir(support.Config.ModListLocation)
+
+	req, err := http.NewRequestWithContext(job.ctx, http.MethodGet, modDownloadUrl(mod), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if errors.Is(err, support.ErrHTTPBudget) {
+		return support.ErrHTTPBudget
+	}
+	if err != nil {
+		return errors.New("connection error")
+	}
+	defer resp.Body.Close()
+	if strings.Contains(resp.Request.URL.Path, "login") {
+		return errDownloadLogin
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("mod portal responded with %s", resp.Status)
+	}
+	if resp.ContentLength < 0 {
+		return errors.New("content length error")
+	}
+
+	file, err := os.CreateTemp(baseDir, ".download-*")
+	if err != nil {
+		return fmt.Errorf("error opening file for write: %w", err)
+	}
+	tmpPath := file.Name()
+
+	counter := &support.WriteCounter{Total: uint64(resp.ContentLength)}
+	d.Lock()
+	job.counter = counter
+	d.Unlock()
+	progress := support.ProgressUpdate{
+		WriteCounter: counter,
+		Message:      message,
+		Progress:     support.FormatNamed(support.Config.Messages.DownloadProgress, "file", mod.FileName),
+		Finished:     support.FormatNamed(support.Config.Messages.DownloadComplete, "file", mod.FileName),
+	}
+	go support.DownloadProgressUpdater(s, &progress)
+
+	hash := sha1.New()
+	_, err = io.Copy(io.MultiWriter(file, hash, counter), resp.Body)
+	closeErr := file.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil && mod.SHA1 != "" && mod.SHA1 != hex.EncodeToString(hash.Sum(nil)) {
+		err = errors.New("downloaded file's hashsum is invalid")
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, path.Join(baseDir, mod.FileName))
+	}
+	if err != nil {
+		counter.Error = true
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	storeInModCache(mod, path.Join(baseDir, mod.FileName))
+	return nil
+}
+
+func modDownloadUrl(mod *modRelease) string {
+	return fmt.Sprintf(
+		"https://mods.factorio.com%s?username=%s&token=%s",
+		mod.DownloadUrl,
+		support.Config.Username,
+		support.Config.ModPortalToken,
+	)
+}
+
+// releaseSize requests the size of a release from the mod portal without downloading it.
+// The size is remembered, so the release is requested only once
+func releaseSize(mod *modRelease) (int64, error) {
+	if mod.size != 0 {
+		return mod.size, nil
+	}
+	resp, err := http.Head(modDownloadUrl(mod))
+	if err != nil {
+		return 0, errors.New("connection error")
+	}
+	resp.Body.Close()
+	if strings.Contains(resp.Request.URL.Path, "login") {
+		return 0, errDownloadLogin
+	}
+	if resp.StatusCode != http.StatusOK || resp.ContentLength < 0 {
+		return 0, fmt.Errorf("mod portal responded with %s", resp.Status)
+	}
+	mod.size = resp.ContentLength
+	return mod.size, nil
+}
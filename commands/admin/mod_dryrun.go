@@ -0,0 +1,78 @@
+package admin
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/maxsupermanhd/FactoCord-3.0/v3/support"
+)
+
+// parseDryRun removes --dry-run from the arguments
+func parseDryRun(args []string) ([]string, bool) {
+	var res []string
+	dryRun := false
+	for _, arg := range args {
+		if arg == "--dry-run" {
+			dryRun = true
+		} else {
+			res = append(res, arg)
+		}
+	}
+	return res, dryRun
+}
+
+// modsDryRun describes the changes planned by a transaction that won't be committed
+func modsDryRun(trx *modTransactionT, mods *ModJSON, policy *modPolicyT) string {
+	before := map[string]Mod{}
+	for _, mod := range trx.Before {
+		before[mod.Name] = mod
+	}
+	changes := support.DefaultTextList("\n**mod-list.json:**")
+	for _, mod := range mods.Mods {
+		old, ok := before[mod.Name]
+		delete(before, mod.Name)
+		switch {
+		case !ok:
+			changes.Append("+ " + mod.Name)
+		case old.Enabled != mod.Enabled && mod.Enabled:
+			changes.Append("enable " + mod.Name)
+		case old.Enabled != mod.Enabled:
+			changes.Append("disable " + mod.Name)
+		}
+		if ok && old.Version != mod.Version {
+			changes.Append(fmt.Sprintf("%s version %q -> %q", mod.Name, old.Version, mod.Version))
+		}
+	}
+	for _, mod := range trx.Before {
+		if _, removed := before[mod.Name]; removed {
+			changes.Append("- " + mod.Name)
+		}
+	}
+	changes.None = " no changes"
+
+	toDownload := support.DefaultTextList("\n**Would download:**")
+	var total int64
+	for _, release := range trx.downloads {
+		size, err := releaseSize(release)
+		if err != nil {
+			toDownload.Append(fmt.Sprintf("%s (size unknown: %s)", release.FileName, err))
+			continue
+		}
+		total += size
+		toDownload.Append(fmt.Sprintf("%s (%s)", release.FileName, support.FormatSize(size)))
+	}
+	if toDownload.Len() > 1 {
+		toDownload.Heading = fmt.Sprintf("\n**Would download %s:**", support.FormatSize(total))
+	}
+
+	toDelete := support.DefaultTextList("\n**Would delete:**")
+	for _, file := range trx.Removed {
+		toDelete.Append(path.Base(file.File))
+	}
+
+	approval := support.DefaultTextList("\n**Would need admin approval:**")
+	approval.List = policy.reasons
+
+	return "\n\n**Dry run, nothing was changed**" + changes.Render() +
+		toDownload.RenderNotEmpty() + toDelete.RenderNotEmpty() + approval.RenderNotEmpty()
+}
@@ -0,0 +1,307 @@
+package admin
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/maxsupermanhd/FactoCord-3.0/v3/support"
+)
+
+type modDependencyT struct {
+	prefix  string
+	name    string
+	compare string
+	version string
+}
+
+func parseDependency(dependency string) (*modDependencyT, bool) {
+	match := dependencyRegexp.FindStringSubmatch(dependency)
+	if match == nil {
+		return nil, false
+	}
+	return &modDependencyT{
+		prefix:  match[1],
+		name:    strings.TrimSpace(match[2]),
+		compare: match[3],
+		version: match[4],
+	}, true
+}
+
+func (d *modDependencyT) Kind() string {
+	switch d.prefix {
+	case "!":
+		return "incompatible"
+	case "?", "(?)":
+		return "optional"
+	default:
+		return "required"
+	}
+}
+
+func (d *modDependencyT) Condition() string {
+	if d.compare == "" {
+		return d.name
+	}
+	return fmt.Sprintf("%s %s %s", d.name, d.compare, d.version)
+}
+
+// readModInfo reads info.json from a downloaded mod (a zip archive or an unpacked directory)
+func readModInfo(file *modDescriptionT) (*modInfoJsonT, error) {
+	var data []byte
+	if support.DirExists(file.path) {
+		var err error
+		data, err = os.ReadFile(filepath.Join(file.path, "info.json"))
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		archive, err := zip.OpenReader(file.path)
+		if err != nil {
+			return nil, err
+		}
+		defer archive.Close()
+		for _, f := range archive.File {
+			// info.json is located in the top level directory of the archive
+			if path.Base(f.Name) != "info.json" || strings.Count(strings.Trim(f.Name, "/"), "/") != 1 {
+				continue
+			}
+			reader, err := f.Open()
+			if err != nil {
+				return nil, err
+			}
+			data, err = io.ReadAll(reader)
+			reader.Close()
+			if err != nil {
+				return nil, err
+			}
+			break
+		}
+		if data == nil {
+			return nil, fmt.Errorf("info.json not found in %s", file.path)
+		}
+	}
+	info := &modInfoJsonT{}
+	err := json.Unmarshal(data, info)
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// installedDependencies returns dependencies of all mods from mod-list.json that have files
+func installedDependencies(mods *ModJSON, files *modsFilesT) map[string][]*modDependencyT {
+	res := map[string][]*modDependencyT{}
+	for _, mod := range mods.Mods {
+		versions, ok := files.versions[mod.Name]
+		if !ok {
+			continue
+		}
+		file := currentModFile(versions, mod.Version)
+		info, err := readModInfo(&file)
+		if err != nil {
+			support.Panik(err, "... when reading info.json of "+file.path)
+			continue
+		}
+		res[mod.Name] = []*modDependencyT{}
+		for _, dependency := range info.Dependencies {
+			if dep, ok := parseDependency(dependency); ok {
+				res[mod.Name] = append(res[mod.Name], dep)
+			}
+		}
+	}
+	return res
+}
+
+func modsInfo(mods *ModJSON, args []string) string {
+	if len(args) != 1 {
+		return support.FormatUsage("Usage: " + modSubcommandUsage("info"))
+	}
+	name := args[0]
+	files := matchModsWithFiles(&mods.Mods)
+	dependencies := installedDependencies(mods, files)
+
+	var entry *Mod
+	for i := range mods.Mods {
+		if mods.Mods[i].Name == name {
+			entry = &mods.Mods[i]
+		}
+	}
+	versions := files.versions[name]
+
+	res := "**" + name + "**"
+	switch {
+	case isBuiltinMod(name):
+		res += " built-in"
+	case len(versions) != 0:
+		pinned := ""
+		if entry != nil {
+			pinned = entry.Version
+		}
+		current := currentModFile(versions, pinned)
+		res += " " + current.version.Full
+	default:
+		res += " not installed"
+	}
+	if entry != nil {
+		if entry.Enabled {
+			res += " (enabled)"
+		} else {
+			res += " (disabled)"
+		}
+	} else if !isBuiltinMod(name) {
+		res += " (not in mod-list.json)"
+	}
+	if len(versions) > 1 {
+		var filenames []string
+		for _, file := range versions {
+			filenames = append(filenames, path.Base(file.path))
+		}
+		res += "\nFiles: " + strings.Join(filenames, ", ")
+	}
+
+	deps, installed := dependencies[name]
+	source := ""
+	if !installed && !isBuiltinMod(name) {
+		// the mod isn't downloaded, take dependencies of the latest release from the mod portal
+		response, err := fetchModPortal(name)
+		if err != nil {
+			support.Panik(err, "... when requesting the mod portal")
+			return connectionError(err)
+		}
+		if response.Message == "Mod not found" || len(response.Releases) == 0 {
+			return res + "\nMod not found on the mod portal"
+		}
+		release := response.Releases[len(response.Releases)-1]
+		source = " of the latest release " + release.Version
+		for _, dependency := range release.InfoJson.Dependencies {
+			if dep, ok := parseDependency(dependency); ok {
+				deps = append(deps, dep)
+			}
+		}
+	}
+
+	if !isBuiltinMod(name) {
+		list := support.DefaultTextList("\n**Dependencies" + source + ":**")
+		for _, dep := range deps {
+			line := fmt.Sprintf("%s (%s)", dep.Condition(), dep.Kind())
+			if !isBuiltinMod(dep.name) {
+				if _, ok := files.versions[dep.name]; ok {
+					if dep.prefix == "!" {
+						line += " - **installed**"
+					}
+				} else if dep.prefix != "!" {
+					line += " - not installed"
+				}
+			}
+			list.Append(line)
+		}
+		res += list.Render()
+	}
+
+	dependents := support.DefaultTextList("\n**Installed mods depending on it:**")
+	var names []string
+	for modName := range dependencies {
+		names = append(names, modName)
+	}
+	sort.Strings(names)
+	for _, modName := range names {
+		for _, dep := range dependencies[modName] {
+			if dep.name == name {
+				dependents.Append(fmt.Sprintf("%s (%s)", modName, dep.Kind()))
+			}
+		}
+	}
+	res += dependents.Render()
+	return res
+}
+
+// modsGraph returns enabled mods and their dependencies in the graphviz DOT format
+func modsGraph(mods *ModJSON) string {
+	files := matchModsWithFiles(&mods.Mods)
+	dependencies := installedDependencies(mods, files)
+	enabled := map[string]bool{}
+	for _, mod := range mods.Mods {
+		enabled[mod.Name] = mod.Enabled
+	}
+
+	var b strings.Builder
+	b.WriteString("digraph mods {\n")
+	b.WriteString("\trankdir=LR;\n")
+	b.WriteString("\tnode [shape=box, style=rounded];\n")
+	for _, mod := range mods.Mods {
+		if !mod.Enabled {
+			continue
+		}
+		attrs := ""
+		if isBuiltinMod(mod.Name) {
+			attrs = " [style=\"rounded,filled\", fillcolor=lightgrey]"
+		} else if _, ok := files.versions[mod.Name]; !ok {
+			attrs = " [color=red, label=\"" + mod.Name + "\\n(no files)\"]"
+		}
+		fmt.Fprintf(&b, "\t%q%s;\n", mod.Name, attrs)
+	}
+	for _, mod := range mods.Mods {
+		if !mod.Enabled {
+			continue
+		}
+		for _, dep := range dependencies[mod.Name] {
+			if dep.name == "base" {
+				continue // every mod depends on base
+			}
+			if dep.prefix == "!" && !enabled[dep.name] {
+				continue
+			}
+			if (dep.prefix == "?" || dep.prefix == "(?)") && !enabled[dep.name] {
+				continue
+			}
+			var attrs []string
+			if dep.compare != "" {
+				attrs = append(attrs, fmt.Sprintf("label=%q", dep.compare+" "+dep.version))
+			}
+			switch dep.Kind() {
+			case "optional":
+				attrs = append(attrs, "style=dashed")
+			case "incompatible":
+				attrs = append(attrs, "color=red", "arrowhead=tee")
+			default:
+				if !enabled[dep.name] && !isBuiltinMod(dep.name) {
+					attrs = append(attrs, "color=red") // missing dependency
+				}
+			}
+			fmt.Fprintf(&b, "\t%q -> %q", mod.Name, dep.name)
+			if len(attrs) != 0 {
+				b.WriteString(" [" + strings.Join(attrs, ", ") + "]")
+			}
+			b.WriteString(";\n")
+		}
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+func sendModsGraph(s *discordgo.Session, mods *ModJSON) {
+	count := 0
+	for _, mod := range mods.Mods {
+		if mod.Enabled {
+			count++
+		}
+	}
+	support.SendComplex(s, &discordgo.MessageSend{
+		Content: fmt.Sprintf("Dependency graph of %d enabled mod%s. Render it with `dot -Tsvg mods.dot -o mods.svg`",
+			count, support.PluralS(count)),
+		Files: []*discordgo.File{{
+			Name:        "mods.dot",
+			ContentType: "text/vnd.graphviz",
+			Reader:      strings.NewReader(modsGraph(mods)),
+		}},
+	})
+}
@@ -0,0 +1,295 @@
+package admin
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/maxsupermanhd/FactoCord-3.0/v3/support"
+)
+
+// modListMutex prevents several $mod commands from changing mod-list.json at the same time
+var modListMutex sync.Mutex
+
+type journalFileT struct {
+	File  string `json:"file"`
+	Aside string `json:"aside,omitempty"` // where the file was moved, empty if it was deleted
+}
+
+// modTransactionT is a journal record of a single $mod invocation
+type modTransactionT struct {
+	ID       int            `json:"id"`
+	Time     time.Time      `json:"time"`
+	UserID   string         `json:"user_id"`
+	Username string         `json:"username"`
+	Command  string         `json:"command"`
+	Before   []Mod          `json:"before"`
+	After    []Mod          `json:"after"`
+	Added    []string       `json:"added_files,omitempty"`
+	Removed  []journalFileT `json:"removed_files,omitempty"`
+	Undone   bool           `json:"undone,omitempty"`
+	// some files couldn't be restored, they are kept in the aside dir
+	RestoreFailed bool `json:"restore_failed,omitempty"`
+
+	downloads []*modRelease
+	dryRun    bool // files are not touched, the transaction is only planned
+}
+
+type modJournalT struct {
+	NextID       int               `json:"next_id"`
+	Transactions []modTransactionT `json:"transactions"`
+}
+
+func modJournalEnabled() bool {
+	return support.Config.ModJournal.Dir != ""
+}
+
+func modJournalPath() string {
+	return filepath.Join(support.Config.ModJournal.Dir, "journal.json")
+}
+
+func loadModJournal() (*modJournalT, error) {
+	journal := &modJournalT{NextID: 1}
+	err := support.ReadJSON(modJournalPath(), journal)
+	return journal, err
+}
+
+func (j *modJournalT) save() error {
+	err := os.MkdirAll(support.Config.ModJournal.Dir, 0775)
+	if err != nil {
+		return err
+	}
+	return support.WriteJSON(modJournalPath(), j)
+}
+
+func newModTransaction(m *discordgo.Message, command string, mods *ModJSON) *modTransactionT {
+	trx := &modTransactionT{
+		ID:      1,
+		Time:    time.Now(),
+		Command: command,
+		Before:  append([]Mod{}, mods.Mods...),
+	}
+	if m != nil && m.Author != nil {
+		trx.UserID = m.Author.ID
+		trx.Username = m.Author.Username
+	}
+	if modJournalEnabled() {
+		journal, err := loadModJournal()
+		support.Panik(err, "... when reading the mod journal")
+		trx.ID = journal.NextID
+	}
+	return trx
+}
+
+func (t *modTransactionT) asideDir() string {
+	return filepath.Join(support.Config.ModJournal.Dir, strconv.Itoa(t.ID))
+}
+
+// removeFile moves a mod file aside so that the transaction can be undone
+func (t *modTransactionT) removeFile(file string) error {
+	if t.dryRun {
+		t.Removed = append(t.Removed, journalFileT{File: file})
+		return nil
+	}
+	if !modJournalEnabled() {
+		err := os.Remove(file)
+		if err == nil {
+			t.Removed = append(t.Removed, journalFileT{File: file})
+		}
+		return err
+	}
+	err := os.MkdirAll(t.asideDir(), 0775)
+	if err != nil {
+		return err
+	}
+	aside := filepath.Join(t.asideDir(), filepath.Base(file))
+	err = support.MoveFile(file, aside)
+	if err != nil {
+		return err
+	}
+	t.Removed = append(t.Removed, journalFileT{File: file, Aside: aside})
+	return nil
+}
+
+// trashFile records a file that was moved to the trash
+func (t *modTransactionT) trashFile(file, trashed string) {
+	t.Removed = append(t.Removed, journalFileT{File: file, Aside: trashed})
+}
+
+// download records releases that should be downloaded once the transaction is committed
+func (t *modTransactionT) download(releases ...*modRelease) {
+	baseDir := path.Dir(support.Config.ModListLocation)
+	for _, release := range releases {
+		t.downloads = append(t.downloads, release)
+		t.Added = append(t.Added, path.Join(baseDir, release.FileName))
+	}
+}
+
+// restoreFiles moves removed files back and returns the names of the files that couldn't be restored
+func restoreFiles(files []journalFileT) []string {
+	var failed []string
+	for i := len(files) - 1; i >= 0; i-- {
+		file := files[i]
+		if file.Aside == "" {
+			continue
+		}
+		err := support.MoveFile(file.Aside, file.File)
+		if err != nil {
+			support.Panik(err, "... when restoring "+file.File)
+			failed = append(failed, path.Base(file.File))
+		}
+	}
+	return failed
+}
+
+// restoreFrom restores the files removed since the transaction had n removed files
+func (t *modTransactionT) restoreFrom(n int) []string {
+	failed := restoreFiles(t.Removed[n:])
+	t.Removed = t.Removed[:n]
+	return failed
+}
+
+// keptAsideMessage tells where the files that couldn't be restored are
+func (t *modTransactionT) keptAsideMessage(failed []string) string {
+	return fmt.Sprintf("\n%s couldn't be restored, they are kept in %s", strings.Join(failed, ", "), t.asideDir())
+}
+
+// rollback restores files removed by a transaction that wasn't committed.
+// It returns a message if some files couldn't be restored
+func (t *modTransactionT) rollback() string {
+	if t.dryRun {
+		return ""
+	}
+	failed := restoreFiles(t.Removed)
+	if len(failed) > 0 {
+		return t.keptAsideMessage(failed)
+	}
+	if modJournalEnabled() {
+		_ = os.RemoveAll(t.asideDir())
+	}
+	return ""
+}
+
+// commit records the transaction in the journal and starts the downloads
+func (t *modTransactionT) commit(s *discordgo.Session, mods *ModJSON) {
+	t.After = append([]Mod{}, mods.Mods...)
+	downloads.Enqueue(s, t.downloads...)
+	if !modJournalEnabled() || (reflect.DeepEqual(t.Before, t.After) && len(t.Added) == 0 && len(t.Removed) == 0) {
+		return
+	}
+	journal, err := loadModJournal()
+	if err != nil {
+		support.Panik(err, "... when reading the mod journal")
+		return
+	}
+	journal.Transactions = append(journal.Transactions, *t)
+	journal.NextID = t.ID + 1
+	for len(journal.Transactions) > support.Config.ModJournal.Keep && len(journal.Transactions) > 1 {
+		dropped := journal.Transactions[0]
+		if dropped.RestoreFailed {
+			fmt.Printf("Mod journal: #%d is dropped, files that couldn't be restored are kept in %s\n", dropped.ID, dropped.asideDir())
+		} else {
+			_ = os.RemoveAll(dropped.asideDir())
+		}
+		journal.Transactions = journal.Transactions[1:]
+	}
+	err = journal.save()
+	support.Panik(err, "... when saving the mod journal")
+}
+
+// modsUndo reverts the last transaction that wasn't undone yet
+func modsUndo(mods *ModJSON) (string, bool) {
+	if !modJournalEnabled() {
+		return "Mod journal is disabled", false
+	}
+	journal, err := loadModJournal()
+	if err != nil {
+		support.Panik(err, "... when reading the mod journal")
+		return "Error reading the mod journal", false
+	}
+	var trx *modTransactionT
+	for i := len(journal.Transactions) - 1; i >= 0; i-- {
+		if !journal.Transactions[i].Undone {
+			trx = &journal.Transactions[i]
+			break
+		}
+	}
+	if trx == nil {
+		return "Nothing to undo", false
+	}
+
+	problems := support.DefaultTextList("\n**Problems:**")
+	if !reflect.DeepEqual(mods.Mods, trx.After) {
+		problems.Append("mod-list.json was changed after this command, these changes are lost")
+	}
+	for _, file := range trx.Added {
+		downloads.Cancel(path.Base(file))
+		err := os.Remove(file)
+		if err != nil && !os.IsNotExist(err) {
+			support.Panik(err, "... when undoing a download")
+			problems.Append("error deleting " + path.Base(file))
+		}
+	}
+	for _, file := range trx.Removed {
+		if file.Aside == "" {
+			problems.Append(path.Base(file.File) + " was deleted and can't be restored")
+		}
+	}
+	if failed := restoreFiles(trx.Removed); len(failed) > 0 {
+		problems.Append(strings.TrimPrefix(trx.keptAsideMessage(failed), "\n"))
+		trx.RestoreFailed = true
+	} else {
+		_ = os.RemoveAll(trx.asideDir())
+	}
+	mods.Mods = append([]Mod{}, trx.Before...)
+	trx.Undone = true
+	err = journal.save()
+	support.Panik(err, "... when saving the mod journal")
+
+	return support.FormatUsage(fmt.Sprintf("Undone #%d `$%s`", trx.ID, trx.Command)) + problems.RenderNotEmpty(), true
+}
+
+func modsHistory(args []string) string {
+	if !modJournalEnabled() {
+		return "Mod journal is disabled"
+	}
+	count := 10
+	if len(args) == 1 {
+		var err error
+		count, err = strconv.Atoi(args[0])
+		if err != nil || count <= 0 {
+			return support.FormatUsage("Usage: $mod history <count>?")
+		}
+	} else if len(args) > 1 {
+		return support.FormatUsage("Usage: $mod history <count>?")
+	}
+	journal, err := loadModJournal()
+	if err != nil {
+		support.Panik(err, "... when reading the mod journal")
+		return "Error reading the mod journal"
+	}
+	if len(journal.Transactions) == 0 {
+		return "The history is empty"
+	}
+	list := support.DefaultTextList("**Recent mod changes:**")
+	for i := len(journal.Transactions) - 1; i >= 0 && list.Len() < count; i-- {
+		trx := journal.Transactions[i]
+		line := fmt.Sprintf("#%d %s %s: `$%s`", trx.ID, trx.Time.Format("2006.01.02 15:04"), trx.Username, trx.Command)
+		if len(trx.Added) != 0 || len(trx.Removed) != 0 {
+			line += fmt.Sprintf(" (+%d/-%d files)", len(trx.Added), len(trx.Removed))
+		}
+		if trx.Undone {
+			line = "~~" + line + "~~ undone"
+		}
+		list.Append(line)
+	}
+	return support.FormatUsage(list.Render())
+}
@@ -0,0 +1,247 @@
+package admin
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/maxsupermanhd/FactoCord-3.0/v3/support"
+)
+
+const (
+	policyAllowed = iota
+	policyDenied
+	policyApproval
+)
+
+// modPolicyT applies mod_policy to mods installed by users that are not admins
+type modPolicyT struct {
+	bypass  bool
+	limit   int64 // bytes, 0 - no limit
+	used    int64 // current size of mod files
+	pending []string
+	reasons []string
+}
+
+func newModPolicy(admin bool, files *modsFilesT) *modPolicyT {
+	p := &modPolicyT{
+		bypass: admin,
+		limit:  int64(support.Config.ModPolicy.MaxTotalSize) * 1024 * 1024,
+	}
+	if p.bypass || p.limit == 0 {
+		return p
+	}
+	for _, versions := range files.versions {
+		for _, file := range versions {
+			if info, err := os.Stat(file.path); err == nil {
+				p.used += info.Size()
+			}
+		}
+	}
+	return p
+}
+
+func matchModPatterns(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matched, err := path.Match(pattern, name); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// check checks if a mod can be installed and returns the reason if it can't be
+func (p *modPolicyT) check(release *modRelease) (int, string) {
+	if p.bypass {
+		return policyAllowed, ""
+	}
+	policy := &support.Config.ModPolicy
+	if matchModPatterns(policy.Deny, release.Name) {
+		return policyDenied, "mod is not allowed"
+	}
+	if len(policy.Allow) == 0 && len(policy.AllowedAuthors) == 0 {
+		return policyAllowed, ""
+	}
+	// a mod is allowed if it's on the allowlist or it's made by one of the allowed authors
+	if matchModPatterns(policy.Allow, release.Name) {
+		return policyAllowed, ""
+	}
+	for _, author := range policy.AllowedAuthors {
+		if strings.EqualFold(author, release.Owner) {
+			return policyAllowed, ""
+		}
+	}
+	reason := "mod is not on the allowlist"
+	if len(policy.Allow) == 0 {
+		reason = fmt.Sprintf("mods by %s are not allowed", release.Owner)
+	}
+	if policy.RequireApproval {
+		return policyApproval, reason
+	}
+	return policyDenied, reason
+}
+
+// reserve checks that the release fits into max_total_size. freed is the size of files replaced by the release
+func (p *modPolicyT) reserve(release *modRelease, freed int64) string {
+	if p.bypass || p.limit == 0 {
+		return ""
+	}
+	size, err := releaseSize(release)
+	if err != nil {
+		support.Panik(err, "... when checking size of "+release.FileName)
+		return "error checking the size of the mod"
+	}
+	if p.used-freed+size > p.limit {
+		return fmt.Sprintf("mods would take %s, the limit is %s",
+			support.FormatSize(p.used-freed+size), support.FormatSize(p.limit))
+	}
+	p.used += size - freed
+	return ""
+}
+
+// requireApproval adds a mod to the approval request created after the command finishes
+func (p *modPolicyT) requireApproval(desc *modDescriptionT, reason string) {
+	p.pending = append(p.pending, support.QuoteSpace(desc.String()))
+	p.reasons = append(p.reasons, fmt.Sprintf("%s: %s", desc.String(), reason))
+}
+
+type modRequestT struct {
+	ID       int       `json:"id"`
+	Time     time.Time `json:"time"`
+	UserID   string    `json:"user_id"`
+	Username string    `json:"username"`
+	Action   string    `json:"action"`
+	Mods     []string  `json:"mods"`
+	Reasons  []string  `json:"reasons"`
+}
+
+type modRequestsT struct {
+	NextID   int           `json:"next_id"`
+	Requests []modRequestT `json:"requests"`
+}
+
+func loadModRequests() (*modRequestsT, error) {
+	requests := &modRequestsT{NextID: 1}
+	err := support.ReadJSON(support.Config.ModPolicy.RequestsFile, requests)
+	return requests, err
+}
+
+func (r *modRequestsT) save() error {
+	return support.WriteJSON(support.Config.ModPolicy.RequestsFile, r)
+}
+
+func (r *modRequestsT) find(id string) (int, *modRequestT) {
+	for i := range r.Requests {
+		if strconv.Itoa(r.Requests[i].ID) == strings.TrimPrefix(id, "#") {
+			return i, &r.Requests[i]
+		}
+	}
+	return -1, nil
+}
+
+// submit creates an approval request for mods that need it
+func (p *modPolicyT) submit(trx *modTransactionT, action string) string {
+	if len(p.pending) == 0 {
+		return ""
+	}
+	requests, err := loadModRequests()
+	if err != nil {
+		support.Panik(err, "... when reading mod requests")
+		return "\nError reading mod requests"
+	}
+	request := modRequestT{
+		ID:       requests.NextID,
+		Time:     time.Now(),
+		UserID:   trx.UserID,
+		Username: trx.Username,
+		Action:   action,
+		Mods:     p.pending,
+		Reasons:  p.reasons,
+	}
+	requests.NextID++
+	requests.Requests = append(requests.Requests, request)
+	err = requests.save()
+	if err != nil {
+		support.Panik(err, "... when saving mod requests")
+		return "\nError saving mod requests"
+	}
+	list := support.DefaultTextList("\n**Need admin approval:**")
+	list.List = p.reasons
+	return list.Render() + support.FormatUsage(fmt.Sprintf(
+		"\nRequest #%d is created, an admin can accept it with `$mod approve %d`", request.ID, request.ID,
+	))
+}
+
+func modsRequests() string {
+	requests, err := loadModRequests()
+	if err != nil {
+		support.Panik(err, "... when reading mod requests")
+		return "Error reading mod requests"
+	}
+	if len(requests.Requests) == 0 {
+		return "There are no pending mod requests"
+	}
+	list := support.DefaultTextList("**Pending mod requests:**")
+	for _, request := range requests.Requests {
+		list.Append(fmt.Sprintf("#%d %s %s: `$mod %s %s`",
+			request.ID, request.Time.Format("2006.01.02 15:04"), request.Username, request.Action, strings.Join(request.Mods, " ")))
+		for _, reason := range request.Reasons {
+			list.Append("    " + reason)
+		}
+	}
+	return support.FormatUsage(list.Render() + "\nUse `$mod approve <id>` or `$mod reject <id>`")
+}
+
+// findModRequest returns a pending request without removing it
+func findModRequest(id string) (*modRequestT, string) {
+	requests, err := loadModRequests()
+	if err != nil {
+		support.Panik(err, "... when reading mod requests")
+		return nil, "Error reading mod requests"
+	}
+	_, request := requests.find(id)
+	if request == nil {
+		return nil, fmt.Sprintf("Request %s not found", id)
+	}
+	return request, ""
+}
+
+// takeModRequest removes a request from the pending requests
+func takeModRequest(id string) (*modRequestT, string) {
+	requests, err := loadModRequests()
+	if err != nil {
+		support.Panik(err, "... when reading mod requests")
+		return nil, "Error reading mod requests"
+	}
+	i, request := requests.find(id)
+	if request == nil {
+		return nil, fmt.Sprintf("Request %s not found", id)
+	}
+	res := *request
+	requests.Requests = append(requests.Requests[:i], requests.Requests[i+1:]...)
+	err = requests.save()
+	if err != nil {
+		support.Panik(err, "... when saving mod requests")
+		return nil, "Error saving mod requests"
+	}
+	return &res, ""
+}
+
+func modsReject(args []string) string {
+	if len(args) == 0 {
+		return support.FormatUsage("Usage: $mod reject <id> <reason>?")
+	}
+	request, errs := takeModRequest(args[0])
+	if request == nil {
+		return errs
+	}
+	res := fmt.Sprintf("<@%s> your mod request #%d (`%s %s`) was rejected",
+		request.UserID, request.ID, request.Action, strings.Join(request.Mods, " "))
+	if len(args) > 1 {
+		res += ": " + strings.Join(args[1:], " ")
+	}
+	return res
+}
@@ -0,0 +1,124 @@
+package admin
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+
+	"github.com/maxsupermanhd/FactoCord-3.0/v3/support"
+)
+
+// currentModFile returns the file factorio will load: the version from mod-list.json or the newest one
+func currentModFile(versions []modDescriptionT, pinned string) modDescriptionT {
+	current := versions[0]
+	for _, file := range versions {
+		if file.version.Full == pinned {
+			return file
+		}
+		if file.version.NewerThan(&current.version) {
+			current = file
+		}
+	}
+	return current
+}
+
+type prunableFileT struct {
+	path   string
+	size   int64
+	reason string
+}
+
+// findPrunableFiles returns files of mods that are not in mod-list.json
+// and older versions of mods that have several versions downloaded
+func findPrunableFiles(mods *ModJSON, files *modsFilesT) []prunableFileT {
+	pinned := map[string]string{}
+	for _, mod := range mods.Mods {
+		pinned[mod.Name] = mod.Version
+	}
+	var res []prunableFileT
+	for name, versions := range files.versions {
+		if files.extra[name] {
+			for _, file := range versions {
+				res = append(res, prunableFileT{path: file.path, reason: "not in mod-list.json"})
+			}
+			continue
+		}
+		if len(versions) < 2 {
+			continue
+		}
+		keep := currentModFile(versions, pinned[name])
+		for _, file := range versions {
+			if file.path != keep.path {
+				res = append(res, prunableFileT{path: file.path, reason: "superseded by " + keep.version.Full})
+			}
+		}
+	}
+	for i := range res {
+		if info, err := os.Stat(res[i].path); err == nil {
+			res[i].size = info.Size()
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].path < res[j].path
+	})
+	return res
+}
+
+func modsPrune(mods *ModJSON, args []string, trx *modTransactionT) string {
+	usage := support.FormatUsage("Usage: $mod prune | prune confirm | prune delete")
+	if len(args) > 1 {
+		return usage
+	}
+	action := ""
+	if len(args) == 1 {
+		action = args[0]
+		if action != "confirm" && action != "delete" {
+			return usage
+		}
+	}
+	if action == "confirm" && support.Config.ModTrashDir == "" {
+		return support.FormatUsage("Mod trash is disabled, use `$mod prune delete` to delete the files")
+	}
+
+	prunable := findPrunableFiles(mods, matchModsWithFiles(&mods.Mods))
+	if len(prunable) == 0 {
+		return "There are no orphaned or superseded mod files"
+	}
+	var total int64
+	list := support.DefaultTextList("")
+	for _, file := range prunable {
+		total += file.size
+		list.Append(fmt.Sprintf("%s (%s) - %s", path.Base(file.path), support.FormatSize(file.size), file.reason))
+	}
+	summary := fmt.Sprintf("%d file%s, %s", len(prunable), support.PluralS(len(prunable)), support.FormatSize(total))
+
+	switch action {
+	case "":
+		list.Heading = "**Files that can be pruned (" + summary + "):**"
+		res := list.Render() + "\nRun `$mod prune confirm` to move them to the trash"
+		res += " or `$mod prune delete` to delete them"
+		return support.FormatUsage(res)
+	case "confirm":
+		for _, file := range prunable {
+			trashed, err := moveToTrash(file.path)
+			if err != nil {
+				support.Panik(err, "... when moving "+file.path+" to the trash")
+				return "Error moving " + path.Base(file.path) + " to the trash"
+			}
+			trx.trashFile(file.path, trashed)
+		}
+		list.Heading = "**Moved to the trash (" + summary + "):**"
+		return list.Render() + support.FormatUsage("\nUse `$mod trash restore <file>+` to restore them")
+	default:
+		for _, file := range prunable {
+			err := trx.removeFile(file.path)
+			if err != nil {
+				support.Panik(err, "... when deleting "+file.path)
+				return "Error deleting " + path.Base(file.path)
+			}
+		}
+		list.Heading = "**Deleted (" + summary + "):**"
+		return list.Render()
+	}
+}
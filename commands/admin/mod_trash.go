@@ -0,0 +1,118 @@
+package admin
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/maxsupermanhd/FactoCord-3.0/v3/support"
+)
+
+// moveToTrash moves a mod file into mod_trash_dir and returns its new path
+func moveToTrash(file string) (string, error) {
+	err := os.MkdirAll(support.Config.ModTrashDir, 0775)
+	if err != nil {
+		return "", err
+	}
+	trashed := filepath.Join(support.Config.ModTrashDir, filepath.Base(file))
+	err = support.MoveFile(file, trashed)
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	_ = os.Chtimes(trashed, now, now) // mtime is used as the time the file was trashed
+	return trashed, nil
+}
+
+// restoreFromTrash moves a file from mod_trash_dir back into the mods directory
+func restoreFromTrash(filename string) error {
+	trashed := filepath.Join(support.Config.ModTrashDir, filepath.Base(filename))
+	if !support.FileExists(trashed) {
+		return os.ErrNotExist
+	}
+	return support.MoveFile(trashed, path.Join(path.Dir(support.Config.ModListLocation), filepath.Base(filename)))
+}
+
+func modsTrash(args []string) string {
+	if support.Config.ModTrashDir == "" {
+		return "Mod trash is disabled"
+	}
+	if len(args) == 0 {
+		return modTrashList()
+	}
+	switch args[0] {
+	case "restore":
+		if len(args) < 2 {
+			return support.FormatUsage("Usage: $mod trash restore <file>+")
+		}
+		restored := support.DefaultTextList("**Restored %d files:**")
+		notFound := support.DefaultTextList("\n**%d files weren't found in the trash:**")
+		for _, filename := range args[1:] {
+			err := restoreFromTrash(filename)
+			if os.IsNotExist(err) {
+				notFound.Append(filename)
+			} else if err != nil {
+				support.Panik(err, "... when restoring "+filename+" from the trash")
+				notFound.Append(filename + ": error restoring the file")
+			} else {
+				restored.Append(filename)
+			}
+		}
+		restored.FormatHeaderWithLength()
+		notFound.FormatHeaderWithLength()
+		return restored.Render() + notFound.RenderNotEmpty()
+	case "empty":
+		if len(args) != 1 {
+			return support.FormatUsage("Usage: $mod trash empty")
+		}
+		files, err := os.ReadDir(support.Config.ModTrashDir)
+		if err != nil && !os.IsNotExist(err) {
+			support.Panik(err, "... when reading the mod trash")
+			return "Error reading the mod trash"
+		}
+		count := 0
+		for _, file := range files {
+			err = os.Remove(filepath.Join(support.Config.ModTrashDir, file.Name()))
+			if err != nil {
+				support.Panik(err, "... when emptying the mod trash")
+				return "Error deleting " + file.Name()
+			}
+			count++
+		}
+		return fmt.Sprintf("Deleted %d file%s from the trash", count, support.PluralS(count))
+	default:
+		return support.FormatUsage("Usage: $mod trash | trash restore <file>+ | trash empty")
+	}
+}
+
+func modTrashList() string {
+	files, err := os.ReadDir(support.Config.ModTrashDir)
+	if err != nil && !os.IsNotExist(err) {
+		support.Panik(err, "... when reading the mod trash")
+		return "Error reading the mod trash"
+	}
+	var infos []os.FileInfo
+	for _, file := range files {
+		info, err := file.Info()
+		if err == nil && info.Mode().IsRegular() {
+			infos = append(infos, info)
+		}
+	}
+	if len(infos) == 0 {
+		return "The trash is empty"
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].ModTime().After(infos[j].ModTime())
+	})
+	var total int64
+	list := support.DefaultTextList("")
+	for _, info := range infos {
+		total += info.Size()
+		list.Append(fmt.Sprintf("%s (%s, %s)", info.Name(), support.FormatSize(info.Size()), info.ModTime().Format("2006.01.02 15:04")))
+	}
+	list.Heading = fmt.Sprintf("**%d file%s in the trash (%s):**", len(infos), support.PluralS(len(infos)), support.FormatSize(total))
+	return list.Render() + support.FormatUsage("\nUse `$mod trash restore <file>+` to restore files or `$mod trash empty` to delete them")
+}
@@ -0,0 +1,126 @@
+package admin
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/maxsupermanhd/FactoCord-3.0/v3/support"
+)
+
+func modsVerify(s *discordgo.Session, mods *ModJSON, args []string) string {
+	redownload := false
+	if len(args) > 0 && args[0] == "--redownload" {
+		redownload = true
+		args = args[1:]
+	}
+	selected := map[string]bool{}
+	for _, name := range args {
+		selected[name] = true
+	}
+
+	files := matchModsWithFiles(&mods.Mods)
+	var names []string
+	for name := range files.versions {
+		if len(selected) == 0 || selected[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	verified := 0
+	damaged := support.DefaultTextList("\n**Damaged files:**")
+	unknown := support.DefaultTextList("\n**Unknown local builds:**")
+	missing := support.DefaultTextList("\n**Missing files:**")
+	var toDownload []*modRelease
+	var toDownloadNames []string
+
+	for _, name := range names {
+		response, err := fetchModPortal(name)
+		if err != nil {
+			support.Panik(err, "... when requesting the mod portal")
+			return connectionError(err)
+		}
+		for _, file := range files.versions[name] {
+			filename := path.Base(file.path)
+			var release *modRelease
+			for i := range response.Releases {
+				if response.Releases[i].Version == file.version.Full {
+					release = &response.Releases[i]
+					break
+				}
+			}
+			if release == nil || release.SHA1 == "" {
+				if response.Message == "Mod not found" {
+					unknown.Append(filename + " (mod is not on the mod portal)")
+				} else {
+					unknown.Append(filename + " (version is not on the mod portal)")
+				}
+				continue
+			}
+			hash, err := fileSHA1(file.path)
+			if err != nil {
+				support.Panik(err, "... when calculating sha1 of "+filename)
+				damaged.Append(filename + ": error reading the file")
+				continue
+			}
+			if hash != release.SHA1 {
+				damaged.Append(filename)
+				release.Name = name
+				toDownload = append(toDownload, release)
+				toDownloadNames = append(toDownloadNames, support.QuoteSpace(name))
+				continue
+			}
+			verified++
+		}
+	}
+	for name := range files.missing {
+		if len(selected) == 0 || selected[name] {
+			missing.Append(name)
+		}
+	}
+	sort.Strings(missing.List)
+	for name := range selected {
+		if _, found := files.versions[name]; !found && !files.missing[name] && !isBuiltinMod(name) {
+			missing.Append(name + " (not installed)")
+		}
+	}
+
+	res := fmt.Sprintf("**Verified %d file%s**", verified, support.PluralS(verified))
+	if damaged.IsEmpty() && unknown.IsEmpty() && missing.IsEmpty() {
+		return res + ", everything is fine"
+	}
+	res += damaged.RenderNotEmpty() + unknown.RenderNotEmpty() + missing.RenderNotEmpty()
+	if len(toDownload) != 0 {
+		if redownload {
+			if support.Config.ModPortalToken == "" {
+				res += "\n**No token to download mods**"
+			} else if support.Config.Username == "" {
+				res += "\n**No username to download mods**"
+			} else {
+				downloads.Enqueue(s, toDownload...)
+				res += fmt.Sprintf("\nDownloading %d damaged file%s again", len(toDownload), support.PluralS(len(toDownload)))
+			}
+		} else {
+			res += support.FormatUsage("\nTo download damaged files again run:\n    `$mod verify --redownload " +
+				strings.Join(support.Unique(toDownloadNames), " ") + "`")
+		}
+	}
+	if missing.NotEmpty() {
+		res += support.FormatUsage("\nMissing files can be downloaded with `$mod update <modname>`")
+	}
+	return res
+}
+
+func fileSHA1(filename string) (string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	return fileHash(file)
+}
@@ -12,7 +12,7 @@ var SaveServerDoc = support.CommandDoc{
 }
 
 // SaveServer executes the save command on the server.
-func SaveServer(s *discordgo.Session, args string) {
+func SaveServer(s *discordgo.Session, _ *discordgo.Message, args string) {
 	if len(args) != 0 {
 		support.Send(s, "Save accepts no arguments")
 		return
@@ -45,7 +45,19 @@ func ServerCommandAdminPermission(args string) bool {
 	return strings.TrimSpace(args) != ""
 }
 
-func ServerCommand(s *discordgo.Session, args string) {
+// ServerCommandConfirm asks for confirmation of commands that stop the server
+func ServerCommandConfirm(args string) bool {
+	action, _ := support.SplitDivide(strings.TrimSpace(args), " ")
+	return action == "stop" || action == "restart" || action == "update" || action == "install"
+}
+
+// ServerCommandOutbound tells if the command requests factorio.com
+func ServerCommandOutbound(args string) bool {
+	action, _ := support.SplitDivide(strings.TrimSpace(args), " ")
+	return action == "update" || action == "install"
+}
+
+func ServerCommand(s *discordgo.Session, _ *discordgo.Message, args string) {
 	action, arg := support.SplitDivide(args, " ")
 	switch action {
 	case "":
@@ -15,7 +15,7 @@ var UnbanPlayerDoc = support.CommandDoc{
 }
 
 // UnbanPlayer unbans a player on the server.
-func UnbanPlayer(s *discordgo.Session, args string) {
+func UnbanPlayer(s *discordgo.Session, _ *discordgo.Message, args string) {
 	if strings.ContainsAny(args, " \n\t") {
 		support.SendFormat(s, "Usage: "+UnbanPlayerDoc.Usage)
 		return
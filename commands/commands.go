@@ -15,26 +15,36 @@ import (
 type Command struct {
 	Name string
 
-	Command func(s *discordgo.Session, args string)
+	Command func(s *discordgo.Session, m *discordgo.Message, args string)
 
 	Admin func(args string) bool
-	Doc   *support.CommandDoc
-	Desc  string
+	// Confirm tells if the command has to be confirmed with a button before it runs
+	Confirm func(args string) bool
+	// Outbound tells if the command makes HTTP requests and is limited by rate_limit.http_budget
+	Outbound func(args string) bool
+	Doc      *support.CommandDoc
+	Desc     string
 }
 
 func alwaysAdmin(_ string) bool {
 	return true
 }
 
+func alwaysConfirm(_ string) bool {
+	return true
+}
+
 // Commands is a list of all available commands
-var Commands = [...]Command{
+var Commands = []Command{
 	// Admin Commands
 	{
-		Name:    "server",
-		Command: admin.ServerCommand,
-		Admin:   admin.ServerCommandAdminPermission,
-		Doc:     &admin.ServerCommandDoc,
-		Desc:    "Manage factorio server",
+		Name:     "server",
+		Command:  admin.ServerCommand,
+		Admin:    admin.ServerCommandAdminPermission,
+		Confirm:  admin.ServerCommandConfirm,
+		Outbound: admin.ServerCommandOutbound,
+		Doc:      &admin.ServerCommandDoc,
+		Desc:     "Manage factorio server",
 	},
 	{
 		Name:    "save",
@@ -54,6 +64,7 @@ var Commands = [...]Command{
 		Name:    "ban",
 		Command: admin.BanPlayer,
 		Admin:   alwaysAdmin,
+		Confirm: alwaysConfirm,
 		Doc:     &admin.BanPlayerDoc,
 		Desc:    "Ban a user from the server",
 	},
@@ -68,15 +79,38 @@ var Commands = [...]Command{
 		Name:    "config",
 		Command: admin.ConfigCommand,
 		Admin:   alwaysAdmin,
+		Confirm: admin.ConfigCommandConfirm,
 		Doc:     &admin.ConfigCommandDoc,
 		Desc:    "Manage config.json",
 	},
 	{
-		Name:    "mod",
-		Command: admin.ModCommand,
+		Name:     "mod",
+		Command:  admin.ModCommand,
+		Admin:    alwaysAdmin,
+		Confirm:  admin.ModCommandConfirm,
+		Outbound: admin.ModCommandOutbound,
+		Doc:      &admin.ModCommandDoc,
+		Desc:     "Manage mod-list.json",
+	},
+	{
+		Name:    "audit",
+		Command: admin.AuditCommand,
 		Admin:   alwaysAdmin,
-		Doc:     &admin.ModCommandDoc,
-		Desc:    "Manage mod-list.json",
+		Doc:     &admin.AuditCommandDoc,
+		Desc:    "Search the audit log",
+	},
+	{
+		Name:    "bridge",
+		Command: admin.BridgeCommand,
+		Admin:   alwaysAdmin,
+		Doc:     &admin.BridgeCommandDoc,
+		Desc:    "Mute users on the bridge",
+	},
+	{
+		Name:  "perms",
+		Admin: alwaysAdmin,
+		Doc:   &PermsCommandDoc,
+		Desc:  "Explain who can run a command",
 	},
 
 	// Util Commands
@@ -95,18 +129,48 @@ var Commands = [...]Command{
 		Desc:    "Get server version",
 	},
 	{
-		Name:    "info",
-		Command: utils.GameInfo,
+		Name:     "info",
+		Command:  utils.GameInfo,
+		Admin:    nil,
+		Outbound: alwaysOutbound,
+		Doc:      &utils.InfoDoc,
+		Desc:     "Get server info",
+	},
+	{
+		Name:     "online",
+		Command:  utils.GameOnline,
+		Admin:    nil,
+		Outbound: alwaysOutbound,
+		Doc:      &utils.OnlineDoc,
+		Desc:     "Get players online",
+	},
+	{
+		Name:    "player",
+		Command: utils.PlayerStats,
+		Admin:   nil,
+		Doc:     &utils.PlayerDoc,
+		Desc:    "Show the playtime of a player",
+	},
+	{
+		Name:    "top",
+		Command: utils.TopPlaytime,
 		Admin:   nil,
-		Doc:     &utils.InfoDoc,
-		Desc:    "Get server info",
+		Doc:     &utils.TopDoc,
+		Desc:    "Show the playtime leaderboard",
 	},
 	{
-		Name:    "online",
-		Command: utils.GameOnline,
+		Name:    "link",
+		Command: utils.LinkCommand,
 		Admin:   nil,
-		Doc:     &utils.OnlineDoc,
-		Desc:    "Get players online",
+		Doc:     &utils.LinkDoc,
+		Desc:    "Link your account with your player",
+	},
+	{
+		Name:    "whois",
+		Command: utils.WhoisCommand,
+		Admin:   nil,
+		Doc:     &utils.WhoisDoc,
+		Desc:    "Find the player of a user or the user of a player",
 	},
 	{
 		Name:  "help",
@@ -122,6 +186,12 @@ var Commands = [...]Command{
 	},
 }
 
+func init() {
+	// assigned here because permsCommand refers to Commands
+	findCommand("perms").Command = permsCommand
+	builtinCommands = len(Commands)
+}
+
 func helpCommand(s *discordgo.Session, args string) {
 	if args == "" {
 		helpAllCommands(s)
@@ -129,16 +199,18 @@ func helpCommand(s *discordgo.Session, args string) {
 	}
 	args = strings.ToLower(args)
 	commandName, subcommand := support.SplitDivide(args, " ")
-	for _, command := range Commands {
-		if command.Name == commandName {
-			helpOnCommand(s, command.Doc, subcommand)
+	list := commandList()
+	for i := range list {
+		if list[i].Name == commandName {
+			roleNames := guildRoleNames(s, &list[i])
+			helpOnCommand(s, list[i].Doc, subcommand, permissionRequirements(&list[i], roleNames))
 			return
 		}
 	}
 	support.Send(s, "There's no such command as \""+commandName+"\"")
 }
 
-func helpOnCommand(s *discordgo.Session, command *support.CommandDoc, subcommandName string) {
+func helpOnCommand(s *discordgo.Session, command *support.CommandDoc, subcommandName string, permissions []string) {
 	path := support.Config.Prefix + command.Name
 	if subcommandName != "" {
 		found := false
@@ -176,6 +248,12 @@ func helpOnCommand(s *discordgo.Session, command *support.CommandDoc, subcommand
 		Name:  "**Usage:**",
 		Value: usage,
 	})
+	if len(permissions) > 0 {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:  "**Permissions:**",
+			Value: strings.Join(permissions, "\n"),
+		})
+	}
 	if len(command.Subcommands) > 0 {
 		subcommands := ""
 		for _, subcommand := range command.Subcommands {
@@ -194,29 +272,25 @@ func helpOnCommand(s *discordgo.Session, command *support.CommandDoc, subcommand
 }
 
 func helpAllCommands(s *discordgo.Session) {
-	fields := make([]*discordgo.MessageEmbedField, 0, len(Commands))
+	list := commandList()
+	fields := make([]*discordgo.MessageEmbedField, 0, len(list))
+	all := make([]*Command, len(list))
+	for i := range list {
+		all[i] = &list[i]
+	}
+	roleNames := guildRoleNames(s, all...)
 
-	for _, command := range Commands {
+	for _, command := range list {
 		desc := support.FormatUsage(command.Desc)
-		if roleID, exists := support.Config.CommandRoles[strings.ToLower(command.Name)]; exists {
-			roles, err := s.GuildRoles(support.GuildID)
-			if err != nil {
-				support.Panik(err, "... when querying guild roles")
-				return
-			}
-			found := false
-			for _, role := range roles {
-				if role.ID == roleID {
-					found = true
-					desc = "[Role \"" + role.Name + "\"] " + desc
-					break
-				}
-			}
-			if !found {
-				desc = "[Role not found in guild] " + desc
-			}
-		} else if command.Admin != nil {
+		rules := permissionRules(&command, "")
+		switch {
+		case len(rules) != 0 && rules[0].grants():
+			desc = "[Admin, " + describeRule(rules[0].rule, roleNames) + "] " + desc
+		case command.Admin != nil:
 			desc = "[Admin] " + desc
+		case len(rules) != 0:
+			// a rule that only denies a command that everyone can run
+			desc = "[" + describeRule(rules[0].rule, roleNames) + "] " + desc
 		}
 		fields = append(fields, &discordgo.MessageEmbedField{
 			Name:  support.Config.Prefix + command.Name,
@@ -244,46 +318,44 @@ func RunCommand(input string, s *discordgo.Session, m *discordgo.Message) {
 		return
 	}
 
-	for _, command := range Commands {
-		if strings.ToLower(command.Name) == commandName {
-			execute := false
-			err := ""
+	command := findCommand(commandName)
+	if command == nil {
+		support.SendFormat(s, "Command not found. Try using \"$help\"")
+		return
+	}
+	audit := func(outcome string) {
+		if isPrivileged(command, args) {
+			support.Audit(s, m, commandName, args, outcome)
+		}
+	}
+	if execute, err := checkPermission(s, command, args, m); !execute {
+		audit("denied: " + err)
+		support.Send(s, err)
+	} else if wait := checkRateLimit(command, args, m.Author.ID); wait > 0 {
+		audit("rate limited")
+		support.Send(s, rateLimitMessage(wait))
+	} else if command.Confirm != nil && command.Confirm(args) {
+		audit("waiting for confirmation")
+		support.Confirm(s, m.Author.ID, "`"+support.Config.Prefix+input+"`", func() {
+			command.Command(s, m, args)
+		})
+	} else {
+		audit("executed")
+		command.Command(s, m, args)
+	}
+}
 
-			if command.Admin != nil && command.Admin(args) {
-				if CheckAdmin(m.Author.ID) {
-					execute = true
-				} else {
-					err = "You are not an admin!"
-				}
-			} else {
-				execute = true
-			}
-			if roleID, exists := support.Config.CommandRoles[commandName]; exists {
-				// TODO? role name
-				err = "You don't have the required role"
-				for _, memberRoleID := range m.Member.Roles {
-					if memberRoleID == roleID {
-						execute = true
-					}
-				}
-			}
-			if execute {
-				command.Command(s, args)
-			} else {
-				support.Send(s, err)
-			}
-			return
+func findCommand(commandName string) *Command {
+	list := commandList()
+	for i := range list {
+		if strings.ToLower(list[i].Name) == commandName {
+			return &list[i]
 		}
 	}
-	support.SendFormat(s, "Command not found. Try using \"$help\"")
+	return nil
 }
 
 // CheckAdmin checks if the user attempting to run an admin command is an admin
 func CheckAdmin(ID string) bool {
-	for _, adminID := range support.Config.AdminIDs {
-		if ID == adminID {
-			return true
-		}
-	}
-	return false
+	return support.IsAdmin(ID)
 }
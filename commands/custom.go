@@ -0,0 +1,182 @@
+package commands
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/maxsupermanhd/FactoCord-3.0/v3/support"
+)
+
+// builtinCommands is the number of commands that are not defined in the config
+var builtinCommands int
+
+// commandsMutex guards the replacement of Commands by RegisterCustomCommands
+var commandsMutex sync.RWMutex
+
+// commandList returns Commands. The slice is replaced and never changed, so it can be used without the lock
+func commandList() []Command {
+	commandsMutex.RLock()
+	defer commandsMutex.RUnlock()
+	return Commands
+}
+
+const captureDuration = 2 * time.Second
+
+var customNameRegexp = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+var templateRegexp = regexp.MustCompile(`\{(args|\d+)}`)
+
+// hasControl tells if the string has newlines or other control characters
+func hasControl(s string) bool {
+	return strings.IndexFunc(s, unicode.IsControl) != -1
+}
+
+var luaCommandRegexp = regexp.MustCompile(`^/(?:c|command|sc|silent-command|measured-command)\s`)
+
+// luaEscaper escapes the arguments for Lua strings, so they can't end the string and run their own code
+var luaEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `'`, `\'`)
+
+// expandTemplate substitutes the arguments into a template. The result is always a single line,
+// so the arguments can't add console commands. In Lua commands the arguments are escaped for strings
+func expandTemplate(template string, args string) (string, error) {
+	if hasControl(args) {
+		return "", fmt.Errorf("arguments can't contain line breaks or control characters")
+	}
+	fields, mismatched := support.QuoteSplit(args, "\"")
+	if mismatched {
+		return "", fmt.Errorf("mismatched quotes")
+	}
+	escape := func(s string) string { return s }
+	if luaCommandRegexp.MatchString(template) {
+		escape = luaEscaper.Replace
+	}
+	var err error
+	res := templateRegexp.ReplaceAllStringFunc(template, func(match string) string {
+		name := match[1 : len(match)-1]
+		if name == "args" {
+			return escape(args)
+		}
+		i, _ := strconv.Atoi(name)
+		if i < 1 || i > len(fields) {
+			err = fmt.Errorf("argument %d is missing", i)
+			return ""
+		}
+		return escape(fields[i-1])
+	})
+	if err == nil && hasControl(res) {
+		err = fmt.Errorf("the command has to be a single line")
+	}
+	return res, err
+}
+
+func customCommand(custom support.CustomCommandT) func(s *discordgo.Session, m *discordgo.Message, args string) {
+	return func(s *discordgo.Session, m *discordgo.Message, args string) {
+		usage := custom.Usage
+		if usage == "" {
+			usage = support.Config.Prefix + custom.Name
+		}
+		if custom.Console != "" {
+			command, err := expandTemplate(custom.Console, args)
+			if err != nil {
+				support.Send(s, "Error: "+err.Error()+"\nUsage: "+usage)
+				return
+			}
+			if !custom.Capture {
+				if !support.Factorio.Send(command) {
+					support.Send(s, "The server is not running")
+				}
+				return
+			}
+			output, sent := support.CaptureOutput(command, captureDuration)
+			if !sent {
+				support.Send(s, "The server is not running")
+			} else if len(output) == 0 {
+				support.Send(s, "The server didn't reply")
+			} else {
+				support.ChunkedMessageSend(s, "```\n"+strings.Join(output, "\n")+"\n```")
+			}
+			return
+		}
+		for _, template := range custom.Commands {
+			input, err := expandTemplate(template, args)
+			if err != nil {
+				support.Send(s, "Error: "+err.Error()+"\nUsage: "+usage)
+				return
+			}
+			// custom commands can't call each other, so they can't loop
+			name, _ := support.SplitDivide(input, " ")
+			if i := commandIndex(strings.ToLower(name)); i == -1 || i >= builtinCommands {
+				support.Send(s, "Error: \""+name+"\" is not a built-in command")
+				return
+			}
+			RunCommand(input, s, m)
+		}
+	}
+}
+
+func commandIndex(name string) int {
+	for i, command := range commandList() {
+		if strings.ToLower(command.Name) == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// RegisterCustomCommands adds the commands from custom_commands to Commands, replacing the previous ones
+func RegisterCustomCommands() {
+	// a new slice, so that commands running right now don't see it half-built
+	list := append([]Command{}, commandList()[:builtinCommands]...)
+	exists := func(name string) bool {
+		for i := range list {
+			if strings.ToLower(list[i].Name) == name {
+				return true
+			}
+		}
+		return false
+	}
+	for _, custom := range support.Config.CustomCommands {
+		custom.Name = strings.ToLower(custom.Name)
+		if !customNameRegexp.MatchString(custom.Name) || custom.Name == "help" {
+			fmt.Printf("Custom command \"%s\" is skipped: invalid name\n", custom.Name)
+			continue
+		}
+		if exists(custom.Name) {
+			fmt.Printf("Custom command \"%s\" is skipped: the command already exists\n", custom.Name)
+			continue
+		}
+		if (custom.Console == "") == (len(custom.Commands) == 0) {
+			fmt.Printf("Custom command \"%s\" is skipped: it needs either console or commands\n", custom.Name)
+			continue
+		}
+		doc := &support.CommandDoc{
+			Name:  custom.Name,
+			Usage: custom.Usage,
+			Doc:   custom.Description,
+		}
+		if custom.Console != "" {
+			doc.Doc += "\nRuns `" + custom.Console + "`"
+		} else {
+			doc.Doc += "\nRuns `" + strings.Join(custom.Commands, "`, `") + "`"
+		}
+		command := Command{
+			Name:    custom.Name,
+			Command: customCommand(custom),
+			Doc:     doc,
+			Desc:    custom.Description,
+		}
+		if custom.Permission != "everyone" {
+			command.Admin = alwaysAdmin
+		}
+		list = append(list, command)
+	}
+	commandsMutex.Lock()
+	Commands = list
+	commandsMutex.Unlock()
+}
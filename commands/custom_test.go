@@ -0,0 +1,41 @@
+package commands
+
+import "testing"
+
+func TestExpandTemplate(t *testing.T) {
+	for _, test := range []struct {
+		template, args, expected string
+	}{
+		{
+			`/silent-command game.print("[color=yellow]{args}[/color]")`,
+			`x") game.players[1].admin=true game.print("`,
+			`/silent-command game.print("[color=yellow]x\") game.players[1].admin=true game.print(\"[/color]")`,
+		},
+		{`/c game.print('{args}')`, `it's`, `/c game.print('it\'s')`},
+		{`/sc game.print("{1}")`, `a\`, `/sc game.print("a\\")`},
+		{`/ban {1} {2}`, `Max "being rude"`, `/ban Max being rude`},
+		{`/whisper {1} {args}`, `Max don't`, `/whisper Max Max don't`},
+	} {
+		output, err := expandTemplate(test.template, test.args)
+		if err != nil {
+			t.Errorf("expandTemplate(%q, %q) failed: %s", test.template, test.args, err)
+		} else if output != test.expected {
+			t.Errorf("expandTemplate(%q, %q) = %q, expected %q", test.template, test.args, output, test.expected)
+		}
+	}
+}
+
+func TestExpandTemplateErrors(t *testing.T) {
+	for _, test := range []struct {
+		template, args string
+	}{
+		{`/sc game.print("{args}")`, "a\n/promote b"},
+		{`/sc game.print("{args}")`, "a\r/promote b"},
+		{`/ban {1} {2}`, "Max"},
+		{`/ban {1}`, `"Max`},
+	} {
+		if output, err := expandTemplate(test.template, test.args); err == nil {
+			t.Errorf("expandTemplate(%q, %q) = %q, expected an error", test.template, test.args, output)
+		}
+	}
+}
@@ -0,0 +1,288 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/maxsupermanhd/FactoCord-3.0/v3/support"
+)
+
+var PermsCommandDoc = support.CommandDoc{
+	Name:  "perms",
+	Usage: "$perms check <user> <command> <args>*",
+	Doc: "command explains permissions.\n" +
+		"Commands and subcommands (e.g. `server.restart`, `mod.update`) are configured in `permissions` in the config. " +
+		"A rule grants the command to `roles` and `users` and denies it to `deny_roles` and `deny_users`. " +
+		"Deny rules win over grants, the rule of a subcommand wins over the rule of its command. Admins can run everything.",
+	Subcommands: []support.CommandDoc{
+		{
+			Name:  "check",
+			Usage: "$perms check <user> <command> <args>*",
+			Doc:   "command shows if a user (a mention or an id) can run a command and which rules made the decision",
+		},
+	},
+}
+
+type permissionRuleRefT struct {
+	key  string
+	rule support.PermissionRuleT
+}
+
+func (r *permissionRuleRefT) grants() bool {
+	return len(r.rule.Roles) != 0 || len(r.rule.Users) != 0
+}
+
+// permissionDecisionT is a result of a permission check and the explanation of it
+type permissionDecisionT struct {
+	allowed bool
+	reason  string // shown to the user when the command is denied
+	steps   []string
+}
+
+func (d *permissionDecisionT) step(format string, a ...interface{}) {
+	d.steps = append(d.steps, fmt.Sprintf(format, a...))
+}
+
+func containsString(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAny(list []string, values []string) (string, bool) {
+	for _, value := range values {
+		if containsString(list, value) {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// subcommandName returns the subcommand from the arguments if the command has it
+func subcommandName(command *Command, args string) string {
+	name, _ := support.SplitDivide(strings.TrimSpace(args), " ")
+	name = strings.ToLower(name)
+	for _, subcommand := range command.Doc.Subcommands {
+		if subcommand.Name == name {
+			return name
+		}
+	}
+	return ""
+}
+
+// permissionRules returns the rules of the subcommand and of the command, the most specific first
+func permissionRules(command *Command, subcommand string) []permissionRuleRefT {
+	var res []permissionRuleRefT
+	name := strings.ToLower(command.Name)
+	if subcommand != "" {
+		key := name + "." + subcommand
+		if rule, ok := support.Config.Permissions[key]; ok {
+			res = append(res, permissionRuleRefT{key, rule})
+		}
+	}
+	rule, ok := support.Config.Permissions[name]
+	if roleID, exists := support.Config.CommandRoles[name]; exists {
+		// command_roles is the old way to grant a command to a single role
+		rule.Roles = append(append([]string{}, rule.Roles...), roleID)
+		ok = true
+	}
+	if ok {
+		res = append(res, permissionRuleRefT{name, rule})
+	}
+	return res
+}
+
+func decidePermission(command *Command, args string, userID string, roles []string) *permissionDecisionT {
+	d := &permissionDecisionT{}
+	if support.IsAdmin(userID) {
+		d.allowed = true
+		d.step("the user is an admin (`admin_ids`)")
+		return d
+	}
+	rules := permissionRules(command, subcommandName(command, args))
+	for _, ref := range rules {
+		if containsString(ref.rule.DenyUsers, userID) {
+			d.step("`%s` denies the user", ref.key)
+			d.reason = "You are not allowed to use this command"
+			return d
+		}
+		if role, denied := containsAny(ref.rule.DenyRoles, roles); denied {
+			d.step("`%s` denies the role <@&%s>", ref.key, role)
+			d.reason = "You are not allowed to use this command"
+			return d
+		}
+	}
+	for _, ref := range rules {
+		if !ref.grants() {
+			continue
+		}
+		// only the most specific rule that grants anything is used
+		if containsString(ref.rule.Users, userID) {
+			d.allowed = true
+			d.step("`%s` grants it to the user", ref.key)
+		} else if role, ok := containsAny(ref.rule.Roles, roles); ok {
+			d.allowed = true
+			d.step("`%s` grants it to the role <@&%s>", ref.key, role)
+		} else {
+			d.step("`%s` grants it only to other roles and users", ref.key)
+			d.reason = "You don't have the required role"
+		}
+		return d
+	}
+	if command.Admin != nil && command.Admin(args) {
+		d.step("no rule grants the command and it requires an admin")
+		d.reason = "You are not an admin!"
+		return d
+	}
+	d.allowed = true
+	d.step("the command is available to everyone")
+	return d
+}
+
+// isPrivileged tells if running the command has to be recorded in the audit log
+func isPrivileged(command *Command, args string) bool {
+	if command.Admin != nil && command.Admin(args) {
+		return true
+	}
+	return len(permissionRules(command, subcommandName(command, args))) != 0
+}
+
+// memberRoles returns roles of the message author. Messages in DMs have no member, so it's requested from the guild
+func memberRoles(s *discordgo.Session, m *discordgo.Message) []string {
+	if m.Member != nil {
+		return m.Member.Roles
+	}
+	if m.Author == nil || s == nil {
+		return nil
+	}
+	if member, err := s.State.Member(support.GuildID, m.Author.ID); err == nil {
+		return member.Roles
+	}
+	member, err := s.GuildMember(support.GuildID, m.Author.ID)
+	if err != nil {
+		return nil
+	}
+	return member.Roles
+}
+
+// checkPermission checks if the author of the message can run the command and returns an error message if they can't
+func checkPermission(s *discordgo.Session, command *Command, args string, m *discordgo.Message) (bool, string) {
+	d := decidePermission(command, args, m.Author.ID, memberRoles(s, m))
+	return d.allowed, d.reason
+}
+
+// describeRule lists who is granted and denied by the rule
+func describeRule(rule support.PermissionRuleT, roleNames map[string]string) string {
+	var parts []string
+	for _, role := range rule.Roles {
+		parts = append(parts, "role \""+roleName(role, roleNames)+"\"")
+	}
+	for _, user := range rule.Users {
+		parts = append(parts, "<@"+user+">")
+	}
+	for _, role := range rule.DenyRoles {
+		parts = append(parts, "not role \""+roleName(role, roleNames)+"\"")
+	}
+	for _, user := range rule.DenyUsers {
+		parts = append(parts, "not <@"+user+">")
+	}
+	return strings.Join(parts, ", ")
+}
+
+func roleName(roleID string, roleNames map[string]string) string {
+	if name, ok := roleNames[roleID]; ok {
+		return name
+	}
+	return "not found in guild"
+}
+
+// guildRoleNames returns the names of the roles used in the rules of the commands, nil if no rule has roles
+func guildRoleNames(s *discordgo.Session, commands ...*Command) map[string]string {
+	needed := false
+	for _, command := range commands {
+		refs := permissionRules(command, "")
+		for _, subcommand := range command.Doc.Subcommands {
+			refs = append(refs, permissionRules(command, subcommand.Name)...)
+		}
+		for _, ref := range refs {
+			needed = needed || len(ref.rule.Roles) != 0 || len(ref.rule.DenyRoles) != 0
+		}
+	}
+	if !needed {
+		return nil
+	}
+	roles, err := s.GuildRoles(support.GuildID)
+	if err != nil {
+		support.Panik(err, "... when querying guild roles")
+		return nil
+	}
+	res := map[string]string{}
+	for _, role := range roles {
+		res[role.ID] = role.Name
+	}
+	return res
+}
+
+// permissionRequirements describes who can run the command and its subcommands
+func permissionRequirements(command *Command, roleNames map[string]string) []string {
+	var res []string
+	for _, ref := range permissionRules(command, "") {
+		res = append(res, "`"+ref.key+"`: admins, "+describeRule(ref.rule, roleNames))
+	}
+	for _, subcommand := range command.Doc.Subcommands {
+		for _, ref := range permissionRules(command, subcommand.Name) {
+			if strings.Contains(ref.key, ".") {
+				res = append(res, "`"+ref.key+"`: admins, "+describeRule(ref.rule, roleNames))
+			}
+		}
+	}
+	return res
+}
+
+func permsCommand(s *discordgo.Session, _ *discordgo.Message, args string) {
+	action, args := support.SplitDivide(strings.TrimSpace(args), " ")
+	if action != "check" {
+		support.SendFormat(s, "Usage: "+PermsCommandDoc.Usage)
+		return
+	}
+	fields := strings.Fields(args)
+	if len(fields) < 2 {
+		support.SendFormat(s, "Usage: "+PermsCommandDoc.Usage)
+		return
+	}
+	userID, ok := support.ParseUserMention(fields[0])
+	if !ok {
+		support.Send(s, "Specify a user with a mention or an id")
+		return
+	}
+	member, err := s.GuildMember(support.GuildID, userID)
+	if err != nil {
+		support.Send(s, "User not found in the guild")
+		return
+	}
+	commandName := strings.ToLower(fields[1])
+	command := findCommand(commandName)
+	if command == nil {
+		support.Send(s, "There's no such command as \""+commandName+"\"")
+		return
+	}
+	commandArgs := strings.Join(fields[2:], " ")
+	d := decidePermission(command, commandArgs, member.User.ID, member.Roles)
+
+	input := strings.TrimSpace(support.Config.Prefix + commandName + " " + commandArgs)
+	verdict := "can't"
+	if d.allowed {
+		verdict = "can"
+	}
+	list := support.DefaultTextList(fmt.Sprintf("**%s** %s run `%s`", member.User.Username, verdict, input))
+	list.List = d.steps
+	support.SendComplex(s, &discordgo.MessageSend{
+		Content:         list.Render(),
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
+}
@@ -0,0 +1,71 @@
+package commands
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/maxsupermanhd/FactoCord-3.0/v3/support"
+)
+
+// lastUse remembers when users ran commands: "user/command" and "user" keys
+var lastUse = struct {
+	sync.Mutex
+	times map[string]time.Time
+}{times: map[string]time.Time{}}
+
+func alwaysOutbound(_ string) bool {
+	return true
+}
+
+// cooldown returns the cooldown of the subcommand or of the command
+func cooldown(command *Command, args string) (string, time.Duration) {
+	name := strings.ToLower(command.Name)
+	if subcommand := subcommandName(command, args); subcommand != "" {
+		if seconds, ok := support.Config.RateLimit.Cooldowns[name+"."+subcommand]; ok {
+			return name + "." + subcommand, time.Duration(seconds) * time.Second
+		}
+	}
+	return name, time.Duration(support.Config.RateLimit.Cooldowns[name]) * time.Second
+}
+
+func waitFor(last time.Time, cooldown time.Duration) time.Duration {
+	if last.IsZero() {
+		return 0
+	}
+	return time.Until(last.Add(cooldown))
+}
+
+// checkRateLimit returns how long the user has to wait before running the command. If the command can run, it's recorded
+func checkRateLimit(command *Command, args string, userID string) time.Duration {
+	if support.Config.RateLimit.ExemptAdmins && support.IsAdmin(userID) {
+		return 0
+	}
+	key, commandCooldown := cooldown(command, args)
+	key = userID + "/" + key
+
+	lastUse.Lock()
+	defer lastUse.Unlock()
+	wait := waitFor(lastUse.times[userID], time.Duration(support.Config.RateLimit.UserCooldown)*time.Second)
+	if w := waitFor(lastUse.times[key], commandCooldown); w > wait {
+		wait = w
+	}
+	if command.Outbound != nil && command.Outbound(args) {
+		if w := support.HTTPBudgetWait(); w > wait {
+			wait = w
+		}
+	}
+	if wait > 0 {
+		return wait
+	}
+	now := time.Now()
+	lastUse.times[userID] = now
+	lastUse.times[key] = now
+	return 0
+}
+
+func rateLimitMessage(wait time.Duration) string {
+	return fmt.Sprintf("Slow down a bit, try again in %ds", int(math.Ceil(wait.Seconds())))
+}
@@ -0,0 +1,191 @@
+package commands
+
+import (
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/maxsupermanhd/FactoCord-3.0/v3/commands/admin"
+	"github.com/maxsupermanhd/FactoCord-3.0/v3/support"
+)
+
+const slashArgsOption = "args"
+
+// slashCompletions maps "command subcommand" to the kind of values suggested for its arguments
+var slashCompletions = map[string]string{
+	"mod update":  "mods",
+	"mod remove":  "mods",
+	"mod enable":  "mods",
+	"mod disable": "mods",
+	"mod info":    "mods",
+	"mod verify":  "mods",
+	"kick":        "players",
+	"ban":         "players",
+	"bridge mute": "players",
+	"whois":       "players",
+	"player":      "players",
+	"help":        "commands",
+}
+
+// slashBaseSubcommands names the subcommands that run commands with subcommands without arguments,
+// discord doesn't allow to call such commands without a subcommand
+var slashBaseSubcommands = map[string]string{
+	"server": "status",
+	"link":   "code",
+}
+
+// slashDescription makes a description that discord accepts: a single line of 1-100 characters
+func slashDescription(doc string, fallback string) string {
+	doc, _ = support.SplitDivide(strings.TrimSpace(doc), "\n")
+	doc = strings.TrimSpace(support.FormatUsage(doc))
+	if doc == "" {
+		doc = fallback
+	}
+	if len([]rune(doc)) > 100 {
+		doc = string([]rune(doc)[:97]) + "..."
+	}
+	return doc
+}
+
+func slashArgs(path string, usage string) *discordgo.ApplicationCommandOption {
+	description := "arguments"
+	if usage != "" {
+		description = slashDescription(strings.ReplaceAll(usage, "\n", " | "), description)
+	}
+	_, autocomplete := slashCompletions[path]
+	return &discordgo.ApplicationCommandOption{
+		Type:         discordgo.ApplicationCommandOptionString,
+		Name:         slashArgsOption,
+		Description:  description,
+		Autocomplete: autocomplete,
+	}
+}
+
+// SlashCommands returns application commands generated from the documentation of Commands
+func SlashCommands() []*discordgo.ApplicationCommand {
+	var res []*discordgo.ApplicationCommand
+	for _, command := range commandList() {
+		name := strings.ToLower(command.Name)
+		appCommand := &discordgo.ApplicationCommand{
+			Name:        name,
+			Description: slashDescription(command.Desc, name),
+		}
+		if len(command.Doc.Subcommands) == 0 {
+			appCommand.Options = append(appCommand.Options, slashArgs(name, command.Doc.Usage))
+		} else if base, ok := slashBaseSubcommands[name]; ok {
+			appCommand.Options = append(appCommand.Options, &discordgo.ApplicationCommandOption{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        base,
+				Description: slashDescription(support.Config.Prefix+name+" without arguments", name),
+			})
+		}
+		for _, subcommand := range command.Doc.Subcommands {
+			path := name + " " + subcommand.Name
+			option := &discordgo.ApplicationCommandOption{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        subcommand.Name,
+				Description: slashDescription(subcommand.Doc, path),
+			}
+			// subcommands without usage take no arguments
+			if subcommand.Usage != "" {
+				option.Options = append(option.Options, slashArgs(path, subcommand.Usage))
+			}
+			appCommand.Options = append(appCommand.Options, option)
+		}
+		res = append(res, appCommand)
+	}
+	return res
+}
+
+// SlashInput converts an application command into the text of a prefix command
+func SlashInput(data *discordgo.ApplicationCommandInteractionData) string {
+	words := []string{data.Name}
+	options := data.Options
+	for len(options) != 0 {
+		option := options[0]
+		if option.Type == discordgo.ApplicationCommandOptionSubCommand {
+			if option.Name != slashBaseSubcommands[data.Name] {
+				words = append(words, option.Name)
+			}
+			options = option.Options
+			continue
+		}
+		if args := strings.TrimSpace(option.StringValue()); args != "" {
+			words = append(words, args)
+		}
+		break
+	}
+	return strings.Join(words, " ")
+}
+
+// CanRun checks if the author of the message can run the command from the input
+func CanRun(s *discordgo.Session, input string, m *discordgo.Message) (bool, string) {
+	commandName, args := support.SplitDivide(input, " ")
+	commandName = strings.ToLower(commandName)
+	if commandName == "help" {
+		return true, ""
+	}
+	command := findCommand(commandName)
+	if command == nil {
+		return false, "Command not found"
+	}
+	return checkPermission(s, command, strings.TrimSpace(args), m)
+}
+
+// SlashCompletions suggests values for the argument that is being typed
+func SlashCompletions(data *discordgo.ApplicationCommandInteractionData, players []string) []*discordgo.ApplicationCommandOptionChoice {
+	path := data.Name
+	var focused *discordgo.ApplicationCommandInteractionDataOption
+	options := data.Options
+	for len(options) != 0 && focused == nil {
+		option := options[0]
+		if option.Type == discordgo.ApplicationCommandOptionSubCommand {
+			path += " " + option.Name
+			options = option.Options
+			continue
+		}
+		for _, x := range options {
+			if x.Focused {
+				focused = x
+			}
+		}
+		break
+	}
+	if focused == nil {
+		return nil
+	}
+
+	var values []string
+	switch slashCompletions[path] {
+	case "mods":
+		values = admin.ModListNames()
+	case "players":
+		values = players
+	case "commands":
+		for _, command := range commandList() {
+			values = append(values, strings.ToLower(command.Name))
+		}
+	}
+
+	// only the last argument is completed, the previous ones are kept
+	typed := focused.StringValue()
+	previous, current := "", typed
+	if i := strings.LastIndex(typed, " "); i != -1 && !strings.Contains(typed, "\"") {
+		previous, current = typed[:i+1], typed[i+1:]
+	}
+	var choices []*discordgo.ApplicationCommandOptionChoice
+	for _, value := range values {
+		if len(choices) == 25 {
+			break
+		}
+		if !strings.HasPrefix(strings.ToLower(value), strings.ToLower(current)) {
+			continue
+		}
+		choice := previous + support.QuoteSpace(value)
+		if len(choice) > 100 {
+			continue
+		}
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{Name: choice, Value: choice})
+	}
+	return choices
+}
@@ -41,7 +41,7 @@ type gameInfo struct {
 	Tags     []string `json:"tags"`
 }
 
-func GameInfo(s *discordgo.Session, _ string) {
+func GameInfo(s *discordgo.Session, _ *discordgo.Message, _ string) {
 	if !support.Factorio.IsRunning() {
 		support.Send(s, "The server is not running")
 		return
@@ -0,0 +1,89 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/maxsupermanhd/FactoCord-3.0/v3/support"
+)
+
+var LinkDoc = support.CommandDoc{
+	Name:  "link",
+	Usage: "$link\n$link remove",
+	Doc: "command links your discord account with your factorio player. " +
+		"You get a code in a direct message, type `/link <code>` in the game within 10 minutes. " +
+		"Linked players are mentioned with `@player` and use your avatar in the chat",
+	Subcommands: []support.CommandDoc{
+		{Name: "remove", Doc: "command removes the link of your account"},
+	},
+}
+
+var WhoisDoc = support.CommandDoc{
+	Name:  "whois",
+	Usage: "$whois <player|@user>",
+	Doc:   "command shows the discord user linked with the player or the player linked with the user",
+}
+
+func LinkCommand(s *discordgo.Session, m *discordgo.Message, args string) {
+	switch strings.TrimSpace(args) {
+	case "":
+		code, err := support.NewLinkCode(m.Author.ID)
+		if err != nil {
+			support.Panik(err, "... when generating a link code")
+			support.Send(s, "Sorry, there was an error generating the code")
+			return
+		}
+		channel, err := s.UserChannelCreate(m.Author.ID)
+		if err == nil {
+			_, err = s.ChannelMessageSend(channel.ID, fmt.Sprintf("Type `/link %s` in the game within 10 minutes to link your account", code))
+		}
+		if err != nil {
+			support.Panik(err, "... when sending a link code")
+			support.Send(s, "I couldn't send you a direct message, allow direct messages from server members and try again")
+			return
+		}
+		support.Send(s, "I've sent you the code in a direct message")
+	case "remove":
+		player, err := support.Unlink(m.Author.ID)
+		if err != nil {
+			support.Panik(err, "... when writing "+support.Config.AccountLinksFile)
+			support.Send(s, "Error saving the links")
+			return
+		}
+		if player == "" {
+			support.Send(s, "Your account is not linked")
+			return
+		}
+		// the player loses what role_sync gave them
+		support.SyncPlayerRoles(s, player, false)
+		support.Send(s, "Your account is not linked anymore")
+	default:
+		support.SendFormat(s, "Usage: "+LinkDoc.Usage)
+	}
+}
+
+func WhoisCommand(s *discordgo.Session, _ *discordgo.Message, args string) {
+	target := strings.TrimSpace(args)
+	if target == "" || strings.Contains(target, " ") {
+		support.SendFormat(s, "Usage: "+WhoisDoc.Usage)
+		return
+	}
+	var content string
+	if userID, ok := support.ParseUserMention(target); ok {
+		if player := support.LinkedPlayer(userID); player != "" {
+			content = fmt.Sprintf("<@%s> plays as **%s**", userID, player)
+		} else {
+			content = fmt.Sprintf("<@%s> is not linked with a player", userID)
+		}
+	} else if userID := support.LinkedUser(target); userID != "" {
+		content = fmt.Sprintf("**%s** is <@%s>", target, userID)
+	} else {
+		content = fmt.Sprintf("**%s** is not linked with a discord user", target)
+	}
+	support.SendComplex(s, &discordgo.MessageSend{
+		Content:         content,
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
+}
@@ -104,7 +104,7 @@ func modsFiles() string {
 }
 
 // ModsList returns the list of mods running on the server.
-func ModsList(s *discordgo.Session, args string) {
+func ModsList(s *discordgo.Session, _ *discordgo.Message, args string) {
 	returnEnabled := true
 	returnDisabled := false
 	if args == "on" || args == "" {
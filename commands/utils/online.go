@@ -36,7 +36,7 @@ func getOnline(info *gameInfo) *support.TextListT {
 	return &online
 }
 
-func GameOnline(s *discordgo.Session, _ string) {
+func GameOnline(s *discordgo.Session, _ *discordgo.Message, _ string) {
 	if !support.Factorio.IsRunning() {
 		support.Send(s, "The server is not running")
 		return
@@ -0,0 +1,117 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/maxsupermanhd/FactoCord-3.0/v3/support"
+)
+
+var PlayerDoc = support.CommandDoc{
+	Name:  "player",
+	Usage: "$player <player|@user>",
+	Doc: "command shows when the player was first and last seen, how many sessions they had and their total playtime. " +
+		"A user linked with `$link` can be given instead of the player",
+}
+
+var TopDoc = support.CommandDoc{
+	Name:  "top",
+	Usage: "$top <window>?",
+	Doc: "command shows the players with the most playtime. " +
+		"`window` is `24h`, `7d`, `30d` or `all`, by default `7d`",
+}
+
+// parseTopWindow returns the start of the window, zero for "all"
+func parseTopWindow(s string) (time.Time, bool) {
+	if s == "all" {
+		return time.Time{}, true
+	}
+	if d, err := support.ParseDuration(s); err == nil && d > 0 {
+		return time.Now().Add(-d), true
+	}
+	return time.Time{}, false
+}
+
+func PlayerStats(s *discordgo.Session, _ *discordgo.Message, args string) {
+	player := strings.TrimSpace(args)
+	if player == "" || strings.Contains(player, " ") {
+		support.SendFormat(s, "Usage: "+PlayerDoc.Usage)
+		return
+	}
+	if userID, ok := support.ParseUserMention(player); ok {
+		player = support.LinkedPlayer(userID)
+		if player == "" {
+			support.Send(s, "The user is not linked with a player")
+			return
+		}
+	}
+	stats, ok := support.SessionStats(player)
+	if !ok {
+		support.SendComplex(s, &discordgo.MessageSend{
+			Content:         fmt.Sprintf("**%s** was never seen on the server", player),
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
+		})
+		return
+	}
+	heading := "**" + stats.Player + "**"
+	if userID := support.LinkedUser(stats.Player); userID != "" {
+		heading += " (<@" + userID + ">)"
+	}
+	list := support.DefaultTextList(heading)
+	list.Append("First seen: " + stats.FirstSeen.Format("2006-01-02 15:04"))
+	if stats.Online {
+		list.Append("Last seen: online now")
+	} else {
+		list.Append("Last seen: " + stats.LastSeen.Format("2006-01-02 15:04"))
+	}
+	list.Append("Sessions: " + strconv.Itoa(stats.Sessions))
+	playtime := "Playtime: " + support.FormatDuration(stats.Playtime)
+	if stats.Estimated {
+		playtime += " (estimated)"
+	}
+	list.Append(playtime)
+	support.SendComplex(s, &discordgo.MessageSend{
+		Content:         list.Render(),
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
+}
+
+func TopPlaytime(s *discordgo.Session, _ *discordgo.Message, args string) {
+	window := strings.TrimSpace(args)
+	if window == "" {
+		window = "7d"
+	}
+	since, ok := parseTopWindow(window)
+	if !ok {
+		support.SendFormat(s, "Usage: "+TopDoc.Usage)
+		return
+	}
+	playtimes := support.Playtimes(since)
+	if len(playtimes) == 0 {
+		support.Send(s, "No one played in that time")
+		return
+	}
+	heading := "**Playtime in the last " + window + ":**"
+	if since.IsZero() {
+		heading = "**Playtime of all time:**"
+	}
+	list := support.DefaultTextList(heading)
+	for i, playtime := range playtimes {
+		if i == 10 {
+			break
+		}
+		line := fmt.Sprintf("%d. **%s**", i+1, playtime.Player)
+		if userID := support.LinkedUser(playtime.Player); userID != "" {
+			line += " (<@" + userID + ">)"
+		}
+		list.Append(line + " " + support.FormatDuration(playtime.Playtime))
+	}
+	support.SendComplex(s, &discordgo.MessageSend{
+		Content:         list.Render(),
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
+}
@@ -14,7 +14,7 @@ var VersionDoc = support.CommandDoc{
 If it says that FactoCord version is unknown look into the error.log`,
 }
 
-func VersionString(s *discordgo.Session, _ string) {
+func VersionString(s *discordgo.Session, _ *discordgo.Message, _ string) {
 	factorioVersion, err := support.FactorioVersion()
 	if err != nil {
 		support.Send(s, "Sorry, there was an error checking factorio version")
@@ -90,4 +90,13 @@ script.on_event(defines.events.on_player_muted, function(event)
 end)
 
 
+commands.add_command("link", "<code> - link your Discord account, get the code with $link in Discord", function(command)
+	if not command.player_index or not command.parameter then
+		return
+	end
+	local p = game.players[command.player_index];
+	localised_print({"", "0000-00-00 00:00:00 [DISCORD-LINK] ", p.name, " ", command.parameter})
+end)
+
+
 return FactoCordIntegration;
@@ -0,0 +1,151 @@
+package discord
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/maxsupermanhd/FactoCord-3.0/v3/support"
+)
+
+// how many bridged messages are remembered for edits and deletions
+const bridgedLimit = 1000
+
+type bridgedMessageT struct {
+	author   string
+	authorID string
+	text     string
+}
+
+// bridged remembers the text sent to the game for recent discord messages
+var bridged = struct {
+	sync.Mutex
+	messages map[string]bridgedMessageT
+	order    []string
+}{messages: map[string]bridgedMessageT{}}
+
+func rememberBridged(m *discordgo.Message, text string) {
+	bridged.Lock()
+	defer bridged.Unlock()
+	if _, ok := bridged.messages[m.ID]; !ok {
+		bridged.order = append(bridged.order, m.ID)
+		if len(bridged.order) > bridgedLimit {
+			delete(bridged.messages, bridged.order[0])
+			bridged.order = bridged.order[1:]
+		}
+	}
+	bridged.messages[m.ID] = bridgedMessageT{author: gameName(m.Author), authorID: m.Author.ID, text: text}
+}
+
+func findBridged(messageID string) (bridgedMessageT, bool) {
+	bridged.Lock()
+	defer bridged.Unlock()
+	message, ok := bridged.messages[messageID]
+	return message, ok
+}
+
+func forgetBridged(messageID string) (bridgedMessageT, bool) {
+	bridged.Lock()
+	defer bridged.Unlock()
+	message, ok := bridged.messages[messageID]
+	delete(bridged.messages, messageID)
+	// the id stays in bridged.order until it's pushed out
+	return message, ok
+}
+
+// editDiff returns the changed words of the edit: "old → new", "+new" or "-old"
+func editDiff(before, after string) string {
+	oldWords := strings.Fields(before)
+	newWords := strings.Fields(after)
+	start := 0
+	for start < len(oldWords) && start < len(newWords) && oldWords[start] == newWords[start] {
+		start++
+	}
+	end := 0
+	for end < len(oldWords)-start && end < len(newWords)-start && oldWords[len(oldWords)-1-end] == newWords[len(newWords)-1-end] {
+		end++
+	}
+	removed := strings.Join(oldWords[start:len(oldWords)-end], " ")
+	added := strings.Join(newWords[start:len(newWords)-end], " ")
+	switch {
+	case removed == "":
+		return "[color=#6CFF3B]+[/color] " + added
+	case added == "":
+		return "[color=#FF6B6B]-[/color] " + removed
+	}
+	return removed + " [color=#FFAA3B]→[/color] " + added
+}
+
+// deleteAuditCounts remembers the count of MESSAGE_DELETE audit log entries.
+// Discord merges deletions of the same author in a channel into one entry and increases its count
+var deleteAuditCounts = struct {
+	sync.Mutex
+	counts map[string]string
+}{counts: map[string]string{}}
+
+// deleteAuditDelay gives discord time to write the audit log entry
+const deleteAuditDelay = 2 * time.Second
+
+// deletedByModerator checks the audit log for a deletion of the author's message by someone else.
+// Authors deleting their own messages don't get audit log entries
+func deletedByModerator(s *discordgo.Session, authorID, channelID string) bool {
+	log, err := s.GuildAuditLog(support.GuildID, "", "", int(discordgo.AuditLogActionMessageDelete), 20)
+	if err != nil {
+		support.Panik(err, "... when reading the audit log (the bot needs the \"View Audit Log\" permission)")
+		return false
+	}
+	deleteAuditCounts.Lock()
+	defer deleteAuditCounts.Unlock()
+	found := false
+	counts := map[string]string{}
+	for _, entry := range log.AuditLogEntries {
+		if entry.Options == nil {
+			continue
+		}
+		previous, seen := deleteAuditCounts.counts[entry.ID]
+		counts[entry.ID] = entry.Options.Count
+		if found || entry.TargetID != authorID || entry.UserID == authorID || entry.Options.ChannelID != channelID {
+			continue
+		}
+		if seen {
+			found = previous != entry.Options.Count
+		} else if created, err := discordgo.SnowflakeTimestamp(entry.ID); err == nil {
+			found = time.Since(created) < time.Minute
+		}
+	}
+	deleteAuditCounts.counts = counts
+	return found
+}
+
+func messageDelete(s *discordgo.Session, m *discordgo.MessageDelete) {
+	message, ok := forgetBridged(m.ID)
+	if !ok || support.InboundRoute(m.ChannelID) == nil {
+		return
+	}
+	go func() {
+		time.Sleep(deleteAuditDelay)
+		if deletedByModerator(s, message.authorID, m.ChannelID) {
+			announceDeleted(message, m.ChannelID)
+		}
+	}()
+}
+
+// messageDeleteBulk announces every message, only bots and moderators can delete messages in bulk
+func messageDeleteBulk(s *discordgo.Session, m *discordgo.MessageDeleteBulk) {
+	for _, id := range m.Messages {
+		if message, ok := forgetBridged(id); ok {
+			announceDeleted(message, m.ChannelID)
+		}
+	}
+}
+
+func announceDeleted(message bridgedMessageT, channelID string) {
+	route := support.InboundRoute(channelID)
+	if route == nil {
+		return
+	}
+	support.Factorio.Send(fmt.Sprintf("%s [color=#FF6B6B][message by %s removed][/color]", routeSignature(route), message.author))
+}
@@ -17,6 +17,7 @@ func CacheUpdater(session *discordgo.Session) {
 	for {
 		count := CacheDiscordMembers(session)
 		fmt.Printf("%s: discord members update: %d members\n", time.Now().Format("2006.01.02 15:04:05"), count)
+		support.SyncRoles(session)
 
 		//sleep for 4 hours (caches every 4 hours)
 		time.Sleep(4 * time.Hour)
@@ -74,6 +75,10 @@ func AddMentions(message string) string {
 	words := strings.Split(message, " ")
 	for i, word := range words {
 		if len(word) >= 2 && word[0] == '@' {
+			if userID := support.LinkedUser(word[1:]); userID != "" {
+				words[i] = "<@" + userID + ">"
+				continue
+			}
 			User := SearchForUser(word[1:])
 			if User == nil {
 				continue
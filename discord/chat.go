@@ -10,6 +10,7 @@ import (
 	"github.com/bwmarrin/discordgo"
 
 	"github.com/maxsupermanhd/FactoCord-3.0/v3/commands"
+	"github.com/maxsupermanhd/FactoCord-3.0/v3/commands/admin"
 	"github.com/maxsupermanhd/FactoCord-3.0/v3/support"
 )
 
@@ -44,6 +45,14 @@ func StartSession() {
 func Init() {
 	Session.AddHandler(messageCreate)
 	Session.AddHandler(messageUpdate)
+	Session.AddHandler(messageDelete)
+	Session.AddHandler(messageDeleteBulk)
+	Session.AddHandler(interactionCreate)
+	RegisterSlashCommands(Session)
+	admin.ConfigReloaded = func() {
+		commands.RegisterCustomCommands()
+		RegisterSlashCommands(Session)
+	}
 	// TODO add recover() ↑
 
 	go CacheUpdater(Session)
@@ -73,7 +82,7 @@ func Close() {
 }
 
 func messageCreate(s *discordgo.Session, m *discordgo.MessageCreate) {
-	if m.Author.ID == s.State.User.ID {
+	if m.Author.ID == s.State.User.ID || support.IsOwnWebhook(m.WebhookID) {
 		return
 	}
 
@@ -105,85 +114,147 @@ func messageCreate(s *discordgo.Session, m *discordgo.MessageCreate) {
 			}
 			return
 		}
-		log.Print("[" + m.Author.Username + "] " + m.Content)
-		// Pipes normal chat allowing it to be seen ingame
-		if strings.TrimSpace(m.Content) != "" {
-			// TODO? add color to mentions
-			lines := strings.Split(m.ContentWithMentionsReplaced(), "\n")
-			for i, line := range lines {
-				if i != 0 {
-					line = "[color=#6CFF3B]⬑[/color] " + line
-				}
-				lines[i] = fmt.Sprintf("<%s>: %s", colorUsername(m.Message), line)
-				lines[i] = "[color=white]" + lines[i] + "[/color]"
-				lines[i] = discordSignature + " " + lines[i]
-			}
-			support.Factorio.Send(strings.Join(lines, "\n"))
-		}
-		for _, attachment := range m.Attachments {
-			attachmentType := ""
-			if attachment.Width == 0 {
-				filename := attachment.Filename
-				if len(filename) > 20 {
-					if strings.Contains(filename, ".") {
-						dotIndex := strings.LastIndex(filename, ".")
-						filename = filename[:min(dotIndex, 20)] + "..." + filename[dotIndex:]
-					} else {
-						filename = filename[:20] + "..."
-					}
-				}
-				attachmentType = "file: " + filename
-			} else {
-				attachmentType = fmt.Sprintf("image %dx%d", attachment.Width, attachment.Height)
-			}
-			attachmentType = fmt.Sprintf("[color=#35BFFF][%s][/color]", attachmentType)
-			if strings.TrimSpace(m.Content) != "" {
-				attachmentType = "[color=#6CFF3B]⬑[/color] " + attachmentType
-			}
-			message := fmt.Sprintf("[color=white]<%s>:[/color] %s", colorUsername(m.Message), attachmentType)
-			support.Factorio.Send(discordSignature + " " + message)
+	}
+	if route := support.InboundRoute(m.ChannelID); route != nil {
+		if !discordMuted(s, m.Message) {
+			bridgeToGame(m.Message, route)
 		}
 		return
 	}
 	if m.ChannelID == support.Config.FactorioConsoleChatID {
 		fmt.Println("wrote to console from channel: \"", m.Content, "\"")
 		support.SendTo(s, "wrote "+m.Content, support.Config.FactorioConsoleChatID)
-		support.Factorio.Send(m.Content)
+		if support.Factorio.Send(m.Content) {
+			support.Audit(s, m.Message, "console", m.Content, "sent to the server")
+		} else {
+			support.Audit(s, m.Message, "console", m.Content, "failed: the server is not running")
+		}
 	}
 	return
 }
 
+// discordMuted tells if the author is muted with $bridge mute and tells them about it once
+func discordMuted(s *discordgo.Session, m *discordgo.Message) bool {
+	mute, notify := support.BridgeMuted(support.MuteDiscord, m.Author.ID)
+	if mute == nil {
+		return false
+	}
+	if notify {
+		channel, err := s.UserChannelCreate(m.Author.ID)
+		if err == nil {
+			_, err = s.ChannelMessageSend(channel.ID, "Your messages are not sent to the game "+mute.Describe())
+		}
+		support.Panik(err, "... when telling a user about the bridge mute")
+	}
+	return true
+}
+
+// bridgeToGame sends a discord message to the game chat
+func bridgeToGame(m *discordgo.Message, route *support.RouteT) {
+	signature := routeSignature(route)
+	log.Print("[" + m.Author.Username + "] " + m.Content)
+	text, ok := filterToGame(m, gameText(m))
+	if !ok {
+		return
+	}
+	rememberBridged(m, text)
+	var lines []string
+	if reply := replyContext(m); reply != "" {
+		lines = append(lines, "[color=#6CFF3B]"+reply+"[/color]")
+	}
+	// Pipes normal chat allowing it to be seen ingame
+	if strings.TrimSpace(m.Content) != "" {
+		// TODO? add color to mentions
+		lines = append(lines, wrapLines(text)...)
+	}
+	for i, line := range lines {
+		if i != 0 {
+			line = "[color=#6CFF3B]⬑[/color] " + line
+		}
+		lines[i] = fmt.Sprintf("<%s>: %s", colorUsername(m), line)
+		lines[i] = "[color=white]" + lines[i] + "[/color]"
+		lines[i] = signature + " " + lines[i]
+	}
+	if len(lines) > 0 {
+		support.Factorio.Send(strings.Join(lines, "\n"))
+	}
+	for _, extra := range messageExtras(m) {
+		extra = fmt.Sprintf("[color=#35BFFF][%s][/color]", extra)
+		if len(lines) > 0 {
+			extra = "[color=#6CFF3B]⬑[/color] " + extra
+		}
+		message := fmt.Sprintf("[color=white]<%s>:[/color] %s", colorUsername(m), extra)
+		support.Factorio.Send(signature + " " + message)
+	}
+}
+
+func routeSignature(route *support.RouteT) string {
+	if route.Prefix == "" {
+		return discordSignature
+	}
+	return discordSignature + "[color=#7289DA]" + route.Prefix + "[/color]"
+}
+
 func messageUpdate(s *discordgo.Session, m *discordgo.MessageUpdate) {
 	// TODO? refactor duplicate functions
-	if m.Author == nil || m.Author.ID == s.State.User.ID || m.ChannelID != support.Config.FactorioChannelID {
+	if m.Author == nil || m.Author.ID == s.State.User.ID || support.IsOwnWebhook(m.WebhookID) {
+		return
+	}
+	route := support.InboundRoute(m.ChannelID)
+	if route == nil || discordMuted(s, m.Message) {
+		return
+	}
+	if m.EditedTimestamp == nil {
+		// discord adds link previews to the message without editing it
+		for _, embed := range m.Embeds {
+			if embed.Title != "" {
+				message := fmt.Sprintf("[color=white]<%s>:[/color] [color=#6CFF3B]⬑[/color] [color=#35BFFF][%s][/color]", colorUsername(m.Message), cutLine("link: "+support.EscapeRichText(embed.Title)))
+				support.Factorio.Send(routeSignature(route) + " " + message)
+			}
+		}
+		return
+	}
+	text, ok := filterToGame(m.Message, gameText(m.Message))
+	if !ok {
+		return
+	}
+	previous, known := findBridged(m.ID)
+	if known && previous.text == text {
 		return
 	}
 	log.Print("[" + m.Author.Username + "]* " + m.Content)
+	rememberBridged(m.Message, text)
+	if known && strings.TrimSpace(previous.text) != "" {
+		message := fmt.Sprintf("[color=#FFAA3B]<%s>*:[/color] %s", colorUsername(m.Message), cutLine(editDiff(previous.text, text)))
+		support.Factorio.Send(routeSignature(route) + " [color=white]" + message + "[/color]")
+		return
+	}
 	// Pipes normal chat allowing it to be seen ingame
 	if strings.TrimSpace(m.Content) != "" {
 		// TODO? add color to mentions
-		lines := strings.Split(m.ContentWithMentionsReplaced(), "\n")
+		lines := wrapLines(text)
 		for i, line := range lines {
 			if i != 0 {
 				line = "[color=#6CFF3B]⬑[/color] " + line
 			}
 			lines[i] = fmt.Sprintf("[color=#FFAA3B]<%s>*:[/color] %s", colorUsername(m.Message), line)
 			lines[i] = "[color=white]" + lines[i] + "[/color]"
-			lines[i] = discordSignature + " " + lines[i]
+			lines[i] = routeSignature(route) + " " + lines[i]
 		}
 		support.Factorio.Send(strings.Join(lines, "\n"))
 	}
 }
 
 func colorUsername(message *discordgo.Message) string {
+	name := gameName(message.Author)
 	if support.Config.IngameDiscordUserColors {
 		color := Session.State.UserColor(message.Author.ID, message.ChannelID)
 		if color == 0 { // some error
-			return message.Author.Username
+			return name
 		} else {
-			return fmt.Sprintf("[color=#%06x]%s[/color]", color, message.Author.Username)
+			return fmt.Sprintf("[color=#%06x]%s[/color]", color, name)
 		}
 	} else {
-		return message.Author.Username
+		return name
 	}
 }
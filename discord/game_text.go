@@ -0,0 +1,109 @@
+package discord
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/maxsupermanhd/FactoCord-3.0/v3/support"
+)
+
+var customEmojiRegexp = regexp.MustCompile(`<a?:(\w+):\d+>`)
+
+// gameText returns the content of the message as it should look in the game chat
+func gameText(m *discordgo.Message) string {
+	return support.MarkdownToRichText(customEmojiRegexp.ReplaceAllString(m.ContentWithMentionsReplaced(), ":$1:"))
+}
+
+// filterToGame applies chat_filters.to_game to the text of the message, false if it's dropped
+func filterToGame(m *discordgo.Message, text string) (string, bool) {
+	filtered, dropped, applied := support.Config.ChatFilters.ToGame.Apply(text)
+	if len(applied) > 0 {
+		support.Audit(Session, m, "chat-filter", "to_game "+text, strings.Join(applied, ", "))
+	}
+	return filtered, !dropped
+}
+
+// gameName returns the player linked with the author or the discord username
+func gameName(author *discordgo.User) string {
+	if player := support.LinkedPlayer(author.ID); player != "" {
+		return player
+	}
+	return author.Username
+}
+
+// cutLine shortens the line to ingame_max_line_length
+func cutLine(line string) string {
+	limit := support.Config.IngameMaxLineLength
+	runes := []rune(line)
+	if limit <= 0 || len(runes) <= limit {
+		return line
+	}
+	return string(runes[:limit-1]) + "…"
+}
+
+// wrapLines splits the text into lines no longer than ingame_max_line_length
+func wrapLines(text string) []string {
+	limit := support.Config.IngameMaxLineLength
+	var res []string
+	for _, line := range strings.Split(text, "\n") {
+		runes := []rune(line)
+		for limit > 0 && len(runes) > limit {
+			res = append(res, string(runes[:limit]))
+			runes = runes[limit:]
+		}
+		res = append(res, string(runes))
+	}
+	return res
+}
+
+// replyContext describes the message the user replied to, empty if it's not a reply
+func replyContext(m *discordgo.Message) string {
+	ref := m.ReferencedMessage
+	if ref == nil || ref.Author == nil {
+		return ""
+	}
+	snippet, _, _ := support.Config.ChatFilters.ToGame.Apply(gameText(ref))
+	snippet = strings.TrimSpace(snippet)
+	if i := strings.Index(snippet, "\n"); i != -1 {
+		snippet = snippet[:i] + " …"
+	}
+	if snippet == "" {
+		if extras := messageExtras(ref); len(extras) > 0 {
+			snippet = "[" + extras[0] + "]"
+		}
+	}
+	return cutLine("↪ replying to " + ref.Author.Username + ": " + snippet)
+}
+
+// messageExtras describes attachments, stickers and link previews of the message
+func messageExtras(m *discordgo.Message) []string {
+	var extras []string
+	for _, attachment := range m.Attachments {
+		if attachment.Width == 0 {
+			filename := attachment.Filename
+			if len(filename) > 20 {
+				if strings.Contains(filename, ".") {
+					dotIndex := strings.LastIndex(filename, ".")
+					filename = filename[:min(dotIndex, 20)] + "..." + filename[dotIndex:]
+				} else {
+					filename = filename[:20] + "..."
+				}
+			}
+			extras = append(extras, "file: "+support.EscapeRichText(filename))
+		} else {
+			extras = append(extras, fmt.Sprintf("image %dx%d", attachment.Width, attachment.Height))
+		}
+	}
+	for _, sticker := range m.StickerItems {
+		extras = append(extras, cutLine("sticker: "+support.EscapeRichText(sticker.Name)))
+	}
+	for _, embed := range m.Embeds {
+		if embed.Title != "" {
+			extras = append(extras, cutLine("link: "+support.EscapeRichText(embed.Title)))
+		}
+	}
+	return extras
+}
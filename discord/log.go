@@ -44,10 +44,12 @@ func ProcessFactorioLogLine(line string) {
 		processFactorioChat(strings.TrimSpace(line))
 	} else if factorioLogRegexp.FindString(line) != "" {
 		if strings.Contains(line, "Quitting: multiplayer error.") {
-			support.SendMessage(Session, support.Config.Messages.ServerFail)
+			ProcessServerStop()
+			support.SendRouted(Session, support.RouteServer, support.Config.Messages.ServerFail)
 		}
 		if strings.Contains(line, "Opening socket for broadcast") {
-			support.SendMessage(Session, support.Config.Messages.ServerStart)
+			support.CloseStaleSessions()
+			support.SendRouted(Session, support.RouteServer, support.Config.Messages.ServerStart)
 		}
 		if strings.Contains(line, "Saving finished") {
 			if support.MyLastMessage && strings.HasPrefix(support.LastMessage.Metadata, "save") {
@@ -56,7 +58,7 @@ func ProcessFactorioLogLine(line string) {
 				support.LastMessage.Edit(Session, support.Config.Messages.ServerSave+fmt.Sprintf(" [x%d]", num))
 				support.LastMessage.Metadata = fmt.Sprintf("save%d", num)
 			} else {
-				message := support.SendMessage(Session, support.Config.Messages.ServerSave)
+				message := support.SendRouted(Session, support.RouteSave, support.Config.Messages.ServerSave)
 				if message != nil {
 					message.Metadata = "save1"
 				}
@@ -64,7 +66,8 @@ func ProcessFactorioLogLine(line string) {
 			}
 		}
 		if strings.Contains(line, "Quitting multiplayer connection.") {
-			support.SendMessage(Session, support.Config.Messages.ServerStop)
+			ProcessServerStop()
+			support.SendRouted(Session, support.RouteServer, support.Config.Messages.ServerStop)
 		}
 		// Detect server entering InGame state (ServerMultiplayerManager changing to InGame)
 		if strings.Contains(line, "changing state from(CreatingGame) to(InGame)") {
@@ -74,25 +77,48 @@ func ProcessFactorioLogLine(line string) {
 			support.Factorio.GameID = match[1]
 		}
 	} else {
+		// forwarded messages answer $kick, $ban etc. and are never captured
 		for _, pattern := range forwardMessages {
 			if pattern.FindString(line) != "" {
 				support.Send(Session, line)
 				return
 			}
 		}
+		support.CaptureLine(line)
 	}
 }
 
-var chatStartRegexp = regexp.MustCompile(`^\[(CHAT|JOIN|LEAVE|KICK|BAN|DISCORD|DISCORD-EMBED)]`)
+// playerChatRegexp splits a chat message into the player name and the text, the tag of the player is dropped
+var playerChatRegexp = regexp.MustCompile(`^(\S+?)(?: \[[^\]]*])?: (.*)$`)
 
-func sendPlayerStateMessage(line, template string) bool {
+// integrationChatRegexp matches the chat of a player relayed by control.lua
+var integrationChatRegexp = regexp.MustCompile(`^(?:\(Admin\) )?<([^>]+)> `)
+
+// playerMuted tells if the player is muted with $bridge mute and tells them about it once
+func playerMuted(player string) bool {
+	mute, notify := support.BridgeMuted(support.MutePlayer, player)
+	if mute == nil {
+		return false
+	}
+	if notify {
+		support.Factorio.Send("/whisper " + player + " Your messages are not sent to Discord " + mute.Describe())
+	}
+	return true
+}
+
+// deathRegexp matches deaths reported by control.lua
+var deathRegexp = regexp.MustCompile(`^\*\*.+\*\* (died\.|was killed by )`)
+
+var chatStartRegexp = regexp.MustCompile(`^\[(CHAT|JOIN|LEAVE|KICK|BAN|DISCORD|DISCORD-EMBED|DISCORD-LINK)]`)
+
+func sendPlayerStateMessage(messageType, line, template string) bool {
 	fields := strings.Fields(line)
 	if len(fields) == 0 || template == "" {
 		return false
 	}
 	username := fields[0]
 	message := strings.ReplaceAll(template, "{username}", username)
-	support.SendMessage(Session, message)
+	support.SendRouted(Session, messageType, message)
 	return true
 }
 
@@ -102,7 +128,7 @@ func processFactorioChat(line string) {
 		return
 	}
 	messageType := match[1]
-	integrationMessage := messageType == "DISCORD-EMBED" || messageType == "DISCORD"
+	integrationMessage := messageType == "DISCORD-EMBED" || messageType == "DISCORD" || messageType == "DISCORD-LINK"
 
 	line = strings.TrimLeft(line[len(messageType)+2:], " ")
 	if strings.HasPrefix(line, "<server>") {
@@ -114,8 +140,9 @@ func processFactorioChat(line string) {
 		fields := strings.Fields(line)
 		if len(fields) > 0 {
 			ProcessPlayerJoin(fields[0])
+			support.SyncPlayerRoles(Session, fields[0], true)
 		}
-		if sendPlayerStateMessage(line, support.Config.Messages.PlayerJoin) {
+		if sendPlayerStateMessage(messageType, line, support.Config.Messages.PlayerJoin) {
 			return
 		}
 	case "LEAVE":
@@ -124,10 +151,19 @@ func processFactorioChat(line string) {
 		if len(fields) > 0 {
 			ProcessPlayerLeave(fields[0])
 		}
-		if sendPlayerStateMessage(line, support.Config.Messages.PlayerLeave) {
+		if sendPlayerStateMessage(messageType, line, support.Config.Messages.PlayerLeave) {
 			return
 		}
 	case "DISCORD", "CHAT":
+		line = support.RichTextToMarkdown(line)
+		filtered, dropped, applied := support.Config.ChatFilters.ToDiscord.Apply(line)
+		if len(applied) > 0 {
+			support.Audit(Session, nil, "chat-filter", "to_discord "+line, strings.Join(applied, ", "))
+		}
+		if dropped {
+			return
+		}
+		line = filtered
 		if strings.Contains(line, "@") {
 			line = AddMentions(line)
 			if !support.Config.AllowPingingEveryone {
@@ -136,11 +172,26 @@ func processFactorioChat(line string) {
 			}
 		}
 		if messageType == "DISCORD" && support.Config.HaveServerEssentials {
-			support.Send(Session, line)
+			if match := integrationChatRegexp.FindStringSubmatch(line); match != nil && playerMuted(match[1]) {
+				return
+			}
+			if deathRegexp.MatchString(line) {
+				support.SendRouted(Session, support.RouteDeath, line)
+			} else {
+				support.SendRouted(Session, support.RouteDiscord, line)
+			}
 			return
 		}
 		if !integrationMessage {
-			support.Send(Session, line)
+			match := playerChatRegexp.FindStringSubmatch(line)
+			if messageType == "CHAT" && match != nil {
+				if playerMuted(match[1]) {
+					return
+				}
+				support.SendRoutedAs(Session, messageType, match[1], match[2], line)
+			} else {
+				support.SendRouted(Session, messageType, line)
+			}
 		}
 	case "DISCORD-EMBED":
 		if support.Config.HaveServerEssentials {
@@ -148,16 +199,42 @@ func processFactorioChat(line string) {
 			err := json.Unmarshal([]byte(line), message)
 			if err == nil {
 				message.TTS = false
-				support.SendComplex(Session, message)
+				support.SendRoutedComplex(Session, support.RouteDiscord, message)
 			}
 		}
+	case "DISCORD-LINK":
+		if support.Config.HaveServerEssentials {
+			player, code := support.SplitDivide(line, " ")
+			linkPlayer(player, code)
+		}
 	default:
 		if !integrationMessage {
-			support.Send(Session, line)
+			support.SendRouted(Session, messageType, line)
 		}
 	}
 }
 
+// linkPlayer finishes $link with the code the player typed in the game
+func linkPlayer(player, code string) {
+	userID, previous, err := support.UseLinkCode(player, code)
+	if err != nil {
+		support.Panik(err, "... when writing "+support.Config.AccountLinksFile)
+		support.Factorio.Send("/whisper " + player + " Sorry, there was an error saving the link")
+		return
+	}
+	if userID == "" {
+		support.Factorio.Send("/whisper " + player + " The code is wrong or expired, get a new one with " + support.Config.Prefix + "link in Discord")
+		return
+	}
+	name := userID
+	if user, err := Session.User(userID); err == nil {
+		name = user.Username
+	}
+	support.Factorio.Send("/whisper " + player + " You are linked with " + name + " in Discord")
+	support.SyncPlayerRoles(Session, player, true)
+	support.SyncPlayerRoles(Session, previous, false)
+}
+
 func forwardToConsoleChannel(s *discordgo.Session, lines chan string) {
 	message := ""
 	var timeout <-chan time.Time = nil
@@ -22,25 +22,6 @@ var ActivePlayers = struct {
 	players map[string]*PlayerInfo
 }{players: make(map[string]*PlayerInfo)}
 
-// formatDuration formats a duration in a human-readable format (e.g., "2h 30m 15s")
-func formatDuration(d time.Duration) string {
-	totalSec := int(d.Seconds())
-	hours := totalSec / 3600
-	minutes := (totalSec % 3600) / 60
-	seconds := totalSec % 60
-
-	var parts []string
-	if hours > 0 {
-		parts = append(parts, fmt.Sprintf("%dh", hours))
-	}
-	if minutes > 0 {
-		parts = append(parts, fmt.Sprintf("%dm", minutes))
-	}
-	parts = append(parts, fmt.Sprintf("%ds", seconds))
-
-	return strings.Join(parts, " ")
-}
-
 // InitPlayerWatcher sends a startup message to the target channel
 func InitPlayerWatcher(s *discordgo.Session) {
 	fmt.Println("Player Watcher is now running.")
@@ -58,6 +39,7 @@ func ProcessPlayerJoin(playerName string) {
 	ActivePlayers.Lock()
 	ActivePlayers.players[playerName] = &PlayerInfo{JoinTime: time.Now()}
 	ActivePlayers.Unlock()
+	support.StartSession(playerName)
 
 	if support.Config.PlayerWatcherTargetChannelID == "" {
 		return
@@ -73,17 +55,23 @@ func ProcessPlayerLeave(playerName string) {
 	var playTime string
 	ActivePlayers.Lock()
 	if info, exists := ActivePlayers.players[playerName]; exists {
-		playTime = formatDuration(time.Since(info.JoinTime))
+		playTime = support.FormatDuration(time.Since(info.JoinTime))
 		delete(ActivePlayers.players, playerName)
 	}
 	ActivePlayers.Unlock()
+	// the stored session survives bot restarts
+	if duration, ok := support.EndSession(playerName); ok {
+		playTime = support.FormatDuration(duration)
+	}
 
 	if support.Config.PlayerWatcherTargetChannelID == "" {
 		return
 	}
 
 	var message string
-	if playTime != "" {
+	if stats, ok := support.SessionStats(playerName); ok && playTime != "" {
+		message = fmt.Sprintf("**Leave:**\n%s hat sich ausgeloggt aus dem Server (Spielzeit: %s, gesamt: %s)", playerName, playTime, support.FormatDuration(stats.Playtime))
+	} else if playTime != "" {
 		message = fmt.Sprintf("**Leave:**\n%s hat sich ausgeloggt aus dem Server (Spielzeit: %s)", playerName, playTime)
 	} else {
 		message = fmt.Sprintf("**Leave:**\n%s hat sich ausgeloggt aus dem Server", playerName)
@@ -91,6 +79,14 @@ func ProcessPlayerLeave(playerName string) {
 	support.SendTo(Session, message, support.Config.PlayerWatcherTargetChannelID)
 }
 
+// ProcessServerStop closes the sessions of all players when the server stops or crashes
+func ProcessServerStop() {
+	ActivePlayers.Lock()
+	ActivePlayers.players = make(map[string]*PlayerInfo)
+	ActivePlayers.Unlock()
+	support.CloseAllSessions()
+}
+
 // ProcessServerInGame handles the server entering InGame state
 func ProcessServerInGame() {
 	fmt.Println("Player Watcher: Server is now InGame")
@@ -111,7 +107,8 @@ func HandlePlayerWatcherMessage(s *discordgo.Session, m *discordgo.MessageCreate
 // HandlePlayerCommand handles the !player command
 func HandlePlayerCommand(s *discordgo.Session, m *discordgo.MessageCreate) bool {
 	content := strings.TrimSpace(strings.ToLower(m.Content))
-	if !strings.HasPrefix(content, "!player") {
+	// "!player <name>" is left for $player when the prefix is "!"
+	if !strings.HasPrefix(content, "!player") || strings.Contains(content, " ") {
 		return false
 	}
 
@@ -132,13 +129,19 @@ func HandlePlayerCommand(s *discordgo.Session, m *discordgo.MessageCreate) bool
 
 	var lines []string
 	for name, info := range ActivePlayers.players {
-		dur := formatDuration(now.Sub(info.JoinTime))
+		dur := support.FormatDuration(now.Sub(info.JoinTime))
+		if userID := support.LinkedUser(name); userID != "" {
+			name += " (<@" + userID + ">)"
+		}
 		lines = append(lines, fmt.Sprintf("- %s: online seit %s", name, dur))
 	}
 	ActivePlayers.RUnlock()
 
 	reply := fmt.Sprintf("**Aktive Spieler:** %d\n%s", playerCount, strings.Join(lines, "\n"))
-	_, _ = s.ChannelMessageSend(m.ChannelID, reply)
+	_, _ = s.ChannelMessageSendComplex(m.ChannelID, &discordgo.MessageSend{
+		Content:         reply,
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
 	return true
 }
 
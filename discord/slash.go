@@ -0,0 +1,81 @@
+package discord
+
+import (
+	"sort"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/maxsupermanhd/FactoCord-3.0/v3/commands"
+	"github.com/maxsupermanhd/FactoCord-3.0/v3/support"
+)
+
+// RegisterSlashCommands registers every command as an application command of the guild
+func RegisterSlashCommands(s *discordgo.Session) {
+	if !support.Config.SlashCommands {
+		return
+	}
+	_, err := s.ApplicationCommandBulkOverwrite(s.State.User.ID, support.GuildID, commands.SlashCommands())
+	support.Panik(err, "... when registering slash commands")
+}
+
+func respondEphemeral(s *discordgo.Session, i *discordgo.Interaction, content string) {
+	err := s.InteractionRespond(i, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	})
+	support.Panik(err, "... when responding to an interaction")
+}
+
+func interactionCreate(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	switch i.Type {
+	case discordgo.InteractionApplicationCommandAutocomplete:
+		data := i.ApplicationCommandData()
+		var players []string
+		for name := range GetActivePlayers() {
+			players = append(players, name)
+		}
+		sort.Strings(players)
+		err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionApplicationCommandAutocompleteResult,
+			Data: &discordgo.InteractionResponseData{
+				Choices: commands.SlashCompletions(&data, players),
+			},
+		})
+		support.Panik(err, "... when responding to autocomplete")
+	case discordgo.InteractionMessageComponent:
+		support.HandleConfirmation(s, i)
+	case discordgo.InteractionApplicationCommand:
+		if i.ChannelID != support.Config.FactorioChannelID || i.Member == nil {
+			respondEphemeral(s, i.Interaction, "Commands only work in <#"+support.Config.FactorioChannelID+">")
+			return
+		}
+		data := i.ApplicationCommandData()
+		input := commands.SlashInput(&data)
+		// commands expect a message, so the interaction pretends to be one
+		m := &discordgo.Message{
+			ID:        i.ID,
+			ChannelID: i.ChannelID,
+			GuildID:   i.GuildID,
+			Content:   support.Config.Prefix + input,
+			Author:    i.Member.User,
+			Member:    i.Member,
+		}
+		if ok, reason := commands.CanRun(s, input, m); !ok {
+			respondEphemeral(s, i.Interaction, reason)
+			return
+		}
+		err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content:         "`" + support.Config.Prefix + input + "`",
+				AllowedMentions: &discordgo.MessageAllowedMentions{},
+			},
+		})
+		support.Panik(err, "... when responding to a slash command")
+		support.MyLastMessage = false
+		commands.RunCommand(input, s, m)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"os/signal"
 	"time"
 
+	"github.com/maxsupermanhd/FactoCord-3.0/v3/commands"
 	"github.com/maxsupermanhd/FactoCord-3.0/v3/discord"
 	"github.com/maxsupermanhd/FactoCord-3.0/v3/support"
 )
@@ -19,6 +20,7 @@ func main() {
 	}
 	fmt.Printf("Welcome to FactoCord %s!\n", support.FactoCordVersion)
 	support.Config.MustLoad()
+	commands.RegisterCustomCommands()
 
 	discord.StartSession()
 
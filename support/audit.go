@@ -0,0 +1,136 @@
+package support
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// AuditEntryT is a line of the audit log
+type AuditEntryT struct {
+	Time      time.Time `json:"time"`
+	UserID    string    `json:"user_id"`
+	Username  string    `json:"username"`
+	ChannelID string    `json:"channel_id"`
+	Command   string    `json:"command"`
+	Args      string    `json:"args,omitempty"`
+	Outcome   string    `json:"outcome"`
+}
+
+var auditMutex sync.Mutex
+
+// secretConfigRegexp matches config paths whose values must not be logged
+var secretConfigRegexp = regexp.MustCompile(`(?i)token|password|secret`)
+
+// secretValueRegexp matches a secret word with the value after it, e.g. `token=abc`,
+// `password: "abc"` or `--rcon-password abc`
+var secretValueRegexp = regexp.MustCompile(`(?i)((?:token|password|passwd|secret|api_?key)\w*["']?(?:\s*[:=]\s*|\s+))("[^"]*"|'[^']*'|[^\s,;)]+)`)
+
+// RedactArgs hides secrets in command arguments, console lines and custom command arguments
+func RedactArgs(command, args string) string {
+	if command == "config" {
+		action, rest := SplitDivide(args, " ")
+		path, value := SplitDivide(strings.TrimSpace(rest), " ")
+		if action == "set" && value != "" && secretConfigRegexp.MatchString(path) {
+			return "set " + path + " [redacted]"
+		}
+	}
+	return secretValueRegexp.ReplaceAllString(args, "${1}[redacted]")
+}
+
+// Audit appends an entry to the audit log and posts it to the audit channel
+func Audit(s *discordgo.Session, m *discordgo.Message, command, args, outcome string) {
+	entry := AuditEntryT{
+		Time:    time.Now(),
+		Command: command,
+		Args:    RedactArgs(command, args),
+		Outcome: outcome,
+	}
+	if m != nil {
+		entry.ChannelID = m.ChannelID
+		if m.Author != nil {
+			entry.UserID = m.Author.ID
+			entry.Username = m.Author.Username
+		}
+	}
+
+	if Config.Audit.File != "" {
+		err := appendAuditEntry(&entry)
+		Panik(err, "... when writing the audit log")
+	}
+	if Config.Audit.ChannelID != "" && s != nil {
+		_, err := s.ChannelMessageSendComplex(Config.Audit.ChannelID, &discordgo.MessageSend{
+			Content:         entry.Format(),
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
+		})
+		Panik(err, "... when sending to the audit channel")
+	}
+}
+
+func appendAuditEntry(entry *AuditEntryT) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	auditMutex.Lock()
+	defer auditMutex.Unlock()
+	file, err := os.OpenFile(Config.Audit.File, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0640)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(append(line, '\n'))
+	closeErr := file.Close()
+	if err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Format returns a single line description of the entry
+func (e *AuditEntryT) Format() string {
+	command := e.Command
+	if e.Args != "" {
+		command += " " + e.Args
+	}
+	channel := ""
+	if e.ChannelID != "" {
+		channel = " in <#" + e.ChannelID + ">"
+	}
+	return fmt.Sprintf("%s **%s**%s: `%s` - %s",
+		e.Time.Format("2006.01.02 15:04:05"), e.Username, channel, strings.ReplaceAll(command, "`", "'"), e.Outcome)
+}
+
+// ReadAudit returns entries of the audit log that match the filter
+func ReadAudit(filter func(entry *AuditEntryT) bool) ([]AuditEntryT, error) {
+	auditMutex.Lock()
+	defer auditMutex.Unlock()
+	file, err := os.Open(Config.Audit.File)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var res []AuditEntryT
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		entry := AuditEntryT{}
+		if json.Unmarshal(scanner.Bytes(), &entry) != nil {
+			continue
+		}
+		if filter(&entry) {
+			res = append(res, entry)
+		}
+	}
+	return res, scanner.Err()
+}
@@ -0,0 +1,48 @@
+package support
+
+import (
+	"sync"
+	"time"
+)
+
+type outputCaptureT struct {
+	lines []string
+}
+
+var outputCaptures = struct {
+	sync.Mutex
+	list []*outputCaptureT
+}{}
+
+// CaptureOutput sends a command to the server and collects the output printed during the given time.
+// Only lines that are neither chat nor log messages are collected
+func CaptureOutput(command string, d time.Duration) ([]string, bool) {
+	capture := &outputCaptureT{}
+	outputCaptures.Lock()
+	outputCaptures.list = append(outputCaptures.list, capture)
+	outputCaptures.Unlock()
+
+	sent := Factorio.Send(command)
+	if sent {
+		time.Sleep(d)
+	}
+
+	outputCaptures.Lock()
+	defer outputCaptures.Unlock()
+	for i, x := range outputCaptures.list {
+		if x == capture {
+			outputCaptures.list = append(outputCaptures.list[:i], outputCaptures.list[i+1:]...)
+			break
+		}
+	}
+	return capture.lines, sent
+}
+
+// CaptureLine passes a line of the server output to running captures
+func CaptureLine(line string) {
+	outputCaptures.Lock()
+	defer outputCaptures.Unlock()
+	for _, capture := range outputCaptures.list {
+		capture.lines = append(capture.lines, line)
+	}
+}
@@ -27,23 +27,98 @@ type configT struct {
 	Prefix                  string `json:"prefix"`
 	HaveServerEssentials    bool   `json:"have_server_essentials"`
 	IngameDiscordUserColors bool   `json:"ingame_discord_user_colors"`
+	IngameMaxLineLength     int    `json:"ingame_max_line_length"`
+	SlashCommands           bool   `json:"slash_commands"`
 
 	AllowPingingEveryone bool `json:"allow_pinging_everyone"`
 
 	EnableConsoleChannel  bool   `json:"enable_console_channel"`
 	FactorioConsoleChatID string `json:"factorio_console_chat_id"`
 
+	// Channels bridged with the game in addition to factorio_channel_id
+	Routes []RouteT `json:"routes"`
+	// Joins and leaves of players for $player and $top
+	SessionsFile string `json:"sessions_file"`
+	// Discord users linked with factorio players by $link
+	AccountLinksFile string `json:"account_links_file"`
+	// Discord users and players muted with $bridge mute
+	BridgeMutesFile string `json:"bridge_mutes_file"`
+
+	// Filters for messages from discord to the game and from the game to discord
+	ChatFilters struct {
+		ToGame    ChatFilterT `json:"to_game"`
+		ToDiscord ChatFilterT `json:"to_discord"`
+	} `json:"chat_filters"`
+
+	// Chat of players is posted through a channel webhook under the player's name
+	Webhook struct {
+		Enabled   bool   `json:"enabled"`
+		Name      string `json:"name"`
+		AvatarURL string `json:"avatar_url"`
+	} `json:"webhook"`
+
 	// Player Watcher: forwards JOIN/LEAVE events from source to target channel
 	PlayerWatcherSourceChannelID string `json:"player_watcher_source_channel_id"`
 	PlayerWatcherTargetChannelID string `json:"player_watcher_target_channel_id"`
 
 	AdminIDs     []string          `json:"admin_ids"`
 	CommandRoles map[string]string `json:"command_roles"`
+	// rules for commands ("mod") and subcommands ("mod.update")
+	Permissions map[string]PermissionRuleT `json:"permissions"`
+	// Roles that make linked players admins or put them in a permission group in the game
+	RoleSync []RoleSyncT `json:"role_sync"`
+	// Players role_sync promoted or put in a group, they are reset when they lose the link or leave the guild
+	RoleSyncFile string `json:"role_sync_file"`
+
+	Audit struct {
+		File      string `json:"file"`
+		ChannelID string `json:"channel_id"`
+	} `json:"audit"`
+	RateLimit struct {
+		// seconds a user waits before running a command ("info") or a subcommand ("mod.update") again
+		Cooldowns map[string]int `json:"cooldowns"`
+		// seconds between any two commands of a user
+		UserCooldown int `json:"user_cooldown"`
+		// outbound HTTP calls per minute, 0 - no limit
+		HTTPBudget   int  `json:"http_budget"`
+		ExemptAdmins bool `json:"exempt_admins"`
+	} `json:"rate_limit"`
+
+	// seconds before an unanswered confirmation of a destructive command expires
+	ConfirmTimeout int `json:"confirm_timeout"`
+
+	CustomCommands []CustomCommandT `json:"custom_commands"`
 
 	ModListLocation string `json:"mod_list_location"`
 	Username        string `json:"username"`
 	ModPortalToken  string `json:"mod_portal_token"`
 
+	ModDownloads struct {
+		Workers    int `json:"workers"`
+		Retries    int `json:"retries"`
+		RetryDelay int `json:"retry_delay"`
+	} `json:"mod_downloads"`
+
+	ModCache struct {
+		Dir    string `json:"dir"`
+		MaxAge int    `json:"max_age"`
+	} `json:"mod_cache"`
+	ModTrashDir string `json:"mod_trash_dir"`
+
+	ModPolicy struct {
+		Allow           []string `json:"allow"`
+		Deny            []string `json:"deny"`
+		AllowedAuthors  []string `json:"allowed_authors"`
+		MaxTotalSize    int      `json:"max_total_size"`
+		RequireApproval bool     `json:"require_approval"`
+		RequestsFile    string   `json:"requests_file"`
+	} `json:"mod_policy"`
+
+	ModJournal struct {
+		Dir  string `json:"dir"`
+		Keep int    `json:"keep"`
+	} `json:"mod_journal"`
+
 	Messages struct {
 		BotStartLaunch    string `json:"bot_start"`
 		BotStartOnly      string `json:"bot_start_only"`
@@ -57,11 +132,49 @@ type configT struct {
 		DownloadStart     string `json:"download_start"`
 		DownloadProgress  string `json:"download_progress"`
 		DownloadComplete  string `json:"download_complete"`
+		DownloadCached    string `json:"download_cached"`
 		Unpacking         string `json:"unpacking"`
 		UnpackingComplete string `json:"unpacking_complete"`
 	} `json:"messages"`
 }
 
+// CustomCommandT is a command defined in the config. It runs either a console command or a sequence of commands.
+// Templates can contain {args} (all arguments) and {1}, {2}, ... (single arguments)
+type CustomCommandT struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Usage       string   `json:"usage"`
+	Permission  string   `json:"permission"` // "admin" or "everyone"
+	Console     string   `json:"console"`
+	Commands    []string `json:"commands"`
+	Capture     bool     `json:"capture"` // send the output of the console command to discord
+}
+
+// PermissionRuleT grants a command to roles and users and denies it to others
+type PermissionRuleT struct {
+	Roles     []string `json:"roles"`
+	Users     []string `json:"users"`
+	DenyRoles []string `json:"deny_roles"`
+	DenyUsers []string `json:"deny_users"`
+}
+
+// RoleSyncT gives linked players with the role admin rights or a permission group in the game
+type RoleSyncT struct {
+	RoleID string `json:"role_id"`
+	Admin  bool   `json:"admin"`
+	Group  string `json:"group"`
+}
+
+// IsAdmin checks if the user is listed in admin_ids
+func IsAdmin(userID string) bool {
+	for _, adminID := range Config.AdminIDs {
+		if userID == adminID {
+			return true
+		}
+	}
+	return false
+}
+
 func (conf *configT) MustLoad() {
 	if !FileExists(ConfigPath) {
 		fmt.Println("Error: config.json not found.")
@@ -76,6 +189,7 @@ func (conf *configT) MustLoad() {
 	if err != nil {
 		Critical(err, "... when parsing config.json")
 	}
+	Critical(conf.Validate(), "... when checking config.json")
 }
 
 func (conf *configT) Load() error {
@@ -92,6 +206,10 @@ func (conf *configT) Load() error {
 	if err != nil {
 		return fmt.Errorf("error parsing config.json: %s", err)
 	}
+	err = test.Validate()
+	if err != nil {
+		return fmt.Errorf("error in config.json: %s", err)
+	}
 
 	conf.defaults()
 	err = json5.Unmarshal(contents, &conf)
@@ -99,12 +217,45 @@ func (conf *configT) Load() error {
 	return nil
 }
 
+// Validate checks the values that can't be checked by parsing
+func (conf *configT) Validate() error {
+	err := conf.ChatFilters.ToGame.validate("to_game")
+	if err != nil {
+		return err
+	}
+	return conf.ChatFilters.ToDiscord.validate("to_discord")
+}
+
 func (conf *configT) defaults() {
+	// maps are merged by Unmarshal, without a reset removed rules would survive $config load
+	conf.Permissions = nil
+	conf.RateLimit.Cooldowns = nil
 	conf.Autolaunch = true
 	conf.GameName = "Factorio"
 	conf.Prefix = "$"
 	// conf.HaveServerEssentials = false
 	// conf.IngameDiscordUserColors = false
+	conf.IngameMaxLineLength = 200
+	conf.SlashCommands = true
+	conf.SessionsFile = "./player-sessions.json"
+	conf.AccountLinksFile = "./account-links.json"
+	conf.RoleSyncFile = "./role-sync.json"
+	conf.BridgeMutesFile = "./bridge-mutes.json"
+	conf.ChatFilters.ToGame.MaxLines = 10
+	conf.Webhook.Name = "FactoCord"
+	conf.Webhook.AvatarURL = "https://api.dicebear.com/9.x/identicon/png?seed={username}"
+	conf.ConfirmTimeout = 60
+	conf.Audit.File = "./audit.jsonl"
+	conf.RateLimit.ExemptAdmins = true
+	conf.ModDownloads.Workers = 2
+	conf.ModDownloads.Retries = 3
+	conf.ModDownloads.RetryDelay = 5
+	conf.ModCache.Dir = "./mod-cache"
+	conf.ModCache.MaxAge = 30
+	conf.ModTrashDir = "./mod-trash"
+	conf.ModPolicy.RequestsFile = "./mod-requests.json"
+	conf.ModJournal.Dir = "./mod-journal"
+	conf.ModJournal.Keep = 20
 	conf.Messages.BotStartLaunch = "**:white_check_mark: Bot started! Launching server...**"
 	conf.Messages.BotStartOnly = "**:white_check_mark: Bot started! Autolaunch disabled.**"
 	conf.Messages.BotStop = ":v:"
@@ -117,6 +268,7 @@ func (conf *configT) defaults() {
 	conf.Messages.DownloadStart = ":arrow_down: Downloading {file}..."
 	conf.Messages.DownloadProgress = ":arrow_down: Downloading {file}: {percent}%"
 	conf.Messages.DownloadComplete = ":white_check_mark: Downloaded {file}"
+	conf.Messages.DownloadCached = ":package: {file} restored from the mod cache"
 	conf.Messages.Unpacking = ":pinching_hand: Unpacking {file}..."
 	conf.Messages.UnpackingComplete = ":ok_hand: Server updated to {version}"
 }
@@ -0,0 +1,143 @@
+package support
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const confirmPrefix = "confirm:"
+
+type confirmationT struct {
+	userID  string
+	summary string
+	action  func()
+	timer   *time.Timer
+}
+
+var confirmations = struct {
+	sync.Mutex
+	nextID  int
+	pending map[int]*confirmationT
+}{pending: map[int]*confirmationT{}}
+
+// Confirm sends the summary with Confirm/Cancel buttons and runs the action when the user or an admin confirms it
+func Confirm(s *discordgo.Session, userID string, summary string, action func()) {
+	timeout := time.Duration(Config.ConfirmTimeout) * time.Second
+
+	confirmations.Lock()
+	confirmations.nextID++
+	id := confirmations.nextID
+	confirmations.Unlock()
+
+	customID := confirmPrefix + strconv.Itoa(id)
+	message := SendComplex(s, &discordgo.MessageSend{
+		Content:         fmt.Sprintf("%s\n<@%s>, are you sure? This request expires in %d seconds", summary, userID, Config.ConfirmTimeout),
+		AllowedMentions: &discordgo.MessageAllowedMentions{Users: []string{userID}},
+		Components: []discordgo.MessageComponent{
+			discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+				discordgo.Button{Label: "Confirm", Style: discordgo.DangerButton, CustomID: customID + ":yes"},
+				discordgo.Button{Label: "Cancel", Style: discordgo.SecondaryButton, CustomID: customID + ":no"},
+			}},
+		},
+	})
+	if message == nil {
+		return
+	}
+
+	confirmation := &confirmationT{
+		userID:  userID,
+		summary: summary,
+		action:  action,
+	}
+	// the entry is registered before the timer starts, so that a short timeout finds it.
+	// The timer is set under the lock because a click can stop it right away
+	confirmations.Lock()
+	confirmations.pending[id] = confirmation
+	confirmation.timer = time.AfterFunc(timeout, func() {
+		if takeConfirmation(id) == nil {
+			return
+		}
+		Audit(s, nil, "confirm", strings.Trim(summary, "`"), "expired")
+		content := summary + "\n*Expired*"
+		_, err := s.ChannelMessageEditComplex(&discordgo.MessageEdit{
+			ID:         message.ID,
+			Channel:    message.ChannelID,
+			Content:    &content,
+			Components: &[]discordgo.MessageComponent{},
+		})
+		Panik(err, "... when expiring a confirmation")
+	})
+	confirmations.Unlock()
+}
+
+func takeConfirmation(id int) *confirmationT {
+	confirmations.Lock()
+	defer confirmations.Unlock()
+	confirmation := confirmations.pending[id]
+	delete(confirmations.pending, id)
+	return confirmation
+}
+
+// HandleConfirmation handles a press of a button created by Confirm. It returns false if the button is not a confirmation
+func HandleConfirmation(s *discordgo.Session, i *discordgo.InteractionCreate) bool {
+	data := i.MessageComponentData()
+	if !strings.HasPrefix(data.CustomID, confirmPrefix) {
+		return false
+	}
+	idString, answer := SplitDivide(strings.TrimPrefix(data.CustomID, confirmPrefix), ":")
+	id, _ := strconv.Atoi(idString)
+
+	user := i.User
+	if i.Member != nil {
+		user = i.Member.User
+	}
+
+	confirmations.Lock()
+	confirmation := confirmations.pending[id]
+	confirmations.Unlock()
+	if confirmation == nil {
+		respondConfirmation(s, i.Interaction, discordgo.InteractionResponseChannelMessageWithSource, "This request has expired", true)
+		return true
+	}
+	if user == nil || (user.ID != confirmation.userID && !IsAdmin(user.ID)) {
+		respondConfirmation(s, i.Interaction, discordgo.InteractionResponseChannelMessageWithSource,
+			fmt.Sprintf("Only <@%s> or an admin can answer this", confirmation.userID), true)
+		return true
+	}
+	if takeConfirmation(id) == nil {
+		return true // someone has pressed a button at the same time
+	}
+	confirmation.timer.Stop()
+
+	m := &discordgo.Message{ChannelID: i.ChannelID, Author: user}
+	if answer != "yes" {
+		Audit(s, m, "confirm", strings.Trim(confirmation.summary, "`"), "cancelled")
+		respondConfirmation(s, i.Interaction, discordgo.InteractionResponseUpdateMessage,
+			confirmation.summary+"\n*Cancelled by "+user.Username+"*", false)
+		return true
+	}
+	Audit(s, m, "confirm", strings.Trim(confirmation.summary, "`"), "confirmed")
+	respondConfirmation(s, i.Interaction, discordgo.InteractionResponseUpdateMessage,
+		confirmation.summary+"\n*Confirmed by "+user.Username+"*", false)
+	MyLastMessage = false
+	confirmation.action()
+	return true
+}
+
+func respondConfirmation(s *discordgo.Session, i *discordgo.Interaction, responseType discordgo.InteractionResponseType, content string, ephemeral bool) {
+	data := &discordgo.InteractionResponseData{
+		Content:         content,
+		Components:      []discordgo.MessageComponent{},
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	}
+	if ephemeral {
+		data.Flags = discordgo.MessageFlagsEphemeral
+	}
+	err := s.InteractionRespond(i, &discordgo.InteractionResponse{Type: responseType, Data: data})
+	Panik(err, "... when responding to a confirmation")
+}
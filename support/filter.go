@@ -0,0 +1,96 @@
+package support
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// ChatFilterRuleT changes or drops messages that match the pattern
+type ChatFilterRuleT struct {
+	Pattern string `json:"pattern"`
+	// "replace" (default) or "drop"
+	Action      string `json:"action"`
+	Replacement string `json:"replacement"`
+}
+
+// ChatFilterT filters messages going in one direction of the bridge
+type ChatFilterT struct {
+	Rules     []ChatFilterRuleT `json:"rules"`
+	StripURLs bool              `json:"strip_urls"`
+	// 0 - no limit
+	MaxLength int `json:"max_length"`
+	MaxLines  int `json:"max_lines"`
+}
+
+// filterRegexps caches compiled patterns, invalid patterns are stored as nil.
+// The config is validated on load, invalid patterns can only come from $config set
+var filterRegexps = struct {
+	sync.Mutex
+	patterns map[string]*regexp.Regexp
+}{patterns: map[string]*regexp.Regexp{}}
+
+var urlRegexp = regexp.MustCompile(`https?://\S+`)
+
+func filterRegexp(pattern string) *regexp.Regexp {
+	filterRegexps.Lock()
+	defer filterRegexps.Unlock()
+	if re, ok := filterRegexps.patterns[pattern]; ok {
+		return re
+	}
+	re, err := regexp.Compile(pattern)
+	Panik(err, "... when compiling the chat filter \""+pattern+"\"")
+	filterRegexps.patterns[pattern] = re
+	return re
+}
+
+// validate checks that every pattern compiles and every action is known
+func (f *ChatFilterT) validate(name string) error {
+	for _, rule := range f.Rules {
+		if _, err := regexp.Compile(rule.Pattern); err != nil {
+			return fmt.Errorf("invalid pattern \"%s\" in chat_filters.%s: %s", rule.Pattern, name, err)
+		}
+		if rule.Action != "" && rule.Action != "replace" && rule.Action != "drop" {
+			return fmt.Errorf("invalid action \"%s\" of \"%s\" in chat_filters.%s", rule.Action, rule.Pattern, name)
+		}
+	}
+	return nil
+}
+
+// Apply filters the text. It returns the filtered text, whether the text is dropped and what was done to it
+func (f *ChatFilterT) Apply(text string) (string, bool, []string) {
+	var applied []string
+	for _, rule := range f.Rules {
+		re := filterRegexp(rule.Pattern)
+		if re == nil && rule.Action == "drop" {
+			// a broken drop rule must not let everything through
+			return "", true, append(applied, "dropped by the invalid "+rule.Pattern)
+		}
+		if re == nil || !re.MatchString(text) {
+			continue
+		}
+		if rule.Action == "drop" {
+			return "", true, append(applied, "dropped by "+rule.Pattern)
+		}
+		replacement := rule.Replacement
+		if replacement == "" {
+			replacement = "***"
+		}
+		text = re.ReplaceAllLiteralString(text, replacement)
+		applied = append(applied, "replaced "+rule.Pattern)
+	}
+	if f.StripURLs && urlRegexp.MatchString(text) {
+		text = urlRegexp.ReplaceAllString(text, "(link)")
+		applied = append(applied, "links removed")
+	}
+	if lines := strings.Split(text, "\n"); f.MaxLines > 0 && len(lines) > f.MaxLines {
+		text = strings.Join(lines[:f.MaxLines], "\n") + fmt.Sprintf("\n… (%d more lines)", len(lines)-f.MaxLines)
+		applied = append(applied, fmt.Sprintf("cut to %d lines", f.MaxLines))
+	}
+	if runes := []rune(text); f.MaxLength > 0 && len(runes) > f.MaxLength {
+		text = string(runes[:f.MaxLength]) + "…"
+		applied = append(applied, fmt.Sprintf("cut to %d characters", f.MaxLength))
+	}
+	return text, false, applied
+}
@@ -0,0 +1,60 @@
+package support
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// httpCalls remembers the time of outbound HTTP requests made during the last minute
+var httpCalls = struct {
+	sync.Mutex
+	times []time.Time
+}{}
+
+type countingTransport struct {
+	http.RoundTripper
+}
+
+// ErrHTTPBudget is returned for requests made after rate_limit.http_budget is used up
+var ErrHTTPBudget = errors.New("the budget of outbound HTTP requests is used up")
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	httpCalls.Lock()
+	trimHTTPCalls()
+	if budget := Config.RateLimit.HTTPBudget; budget > 0 && len(httpCalls.times) >= budget {
+		httpCalls.Unlock()
+		return nil, ErrHTTPBudget
+	}
+	httpCalls.times = append(httpCalls.times, time.Now())
+	httpCalls.Unlock()
+	return t.RoundTripper.RoundTrip(req)
+}
+
+// trimHTTPCalls forgets calls older than a minute. httpCalls has to be locked
+func trimHTTPCalls() {
+	since := time.Now().Add(-time.Minute)
+	i := 0
+	for i < len(httpCalls.times) && httpCalls.times[i].Before(since) {
+		i++
+	}
+	httpCalls.times = httpCalls.times[i:]
+}
+
+func init() {
+	// every http.Get goes through the default client, so all outbound calls are counted
+	http.DefaultClient.Transport = &countingTransport{http.DefaultTransport}
+}
+
+// HTTPBudgetWait returns how long to wait until the budget of outbound HTTP calls allows another call
+func HTTPBudgetWait() time.Duration {
+	budget := Config.RateLimit.HTTPBudget
+	httpCalls.Lock()
+	defer httpCalls.Unlock()
+	trimHTTPCalls()
+	if budget <= 0 || len(httpCalls.times) < budget {
+		return 0
+	}
+	return time.Until(httpCalls.times[len(httpCalls.times)-budget].Add(time.Minute))
+}
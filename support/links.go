@@ -0,0 +1,129 @@
+package support
+
+import (
+	"crypto/rand"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// AccountLinkT connects a discord user with a factorio player
+type AccountLinkT struct {
+	UserID string    `json:"user_id"`
+	Player string    `json:"player"`
+	Linked time.Time `json:"linked"`
+}
+
+// how long a code from $link can be used
+const linkCodeLifetime = 10 * time.Minute
+
+// characters of link codes, without the ones that look alike
+const linkCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+type linkCodeT struct {
+	userID  string
+	expires time.Time
+}
+
+var accountLinks = struct {
+	jsonStoreT[[]AccountLinkT]
+	codes map[string]linkCodeT
+}{
+	jsonStoreT: jsonStoreT[[]AccountLinkT]{filename: func() string { return Config.AccountLinksFile }},
+	codes:      map[string]linkCodeT{},
+}
+
+// NewLinkCode returns a one-time code the user types in the game to link the account
+func NewLinkCode(userID string) (string, error) {
+	code := make([]byte, 6)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(linkCodeAlphabet))))
+		if err != nil {
+			return "", err
+		}
+		code[i] = linkCodeAlphabet[n.Int64()]
+	}
+	accountLinks.Lock()
+	defer accountLinks.Unlock()
+	for c, pending := range accountLinks.codes {
+		if pending.userID == userID || time.Now().After(pending.expires) {
+			delete(accountLinks.codes, c)
+		}
+	}
+	accountLinks.codes[string(code)] = linkCodeT{userID: userID, expires: time.Now().Add(linkCodeLifetime)}
+	return string(code), nil
+}
+
+// UseLinkCode links the player with the user who got the code. It returns the id of the user, empty if the code is wrong,
+// and the player the user was linked with before
+func UseLinkCode(player, code string) (userID string, previous string, err error) {
+	accountLinks.Lock()
+	defer accountLinks.Unlock()
+	code = strings.ToUpper(strings.TrimSpace(code))
+	pending, ok := accountLinks.codes[code]
+	if !ok || time.Now().After(pending.expires) {
+		return "", "", nil
+	}
+	delete(accountLinks.codes, code)
+	accountLinks.load()
+	list := accountLinks.data[:0]
+	for _, link := range accountLinks.data {
+		// a user has one player and a player has one user
+		if link.UserID == pending.userID && !strings.EqualFold(link.Player, player) {
+			previous = link.Player
+		}
+		if link.UserID != pending.userID && !strings.EqualFold(link.Player, player) {
+			list = append(list, link)
+		}
+	}
+	accountLinks.data = append(list, AccountLinkT{UserID: pending.userID, Player: player, Linked: time.Now()})
+	return pending.userID, previous, accountLinks.save()
+}
+
+// Unlink removes the link of the user and returns the player that was linked, empty if there was none
+func Unlink(userID string) (string, error) {
+	accountLinks.Lock()
+	defer accountLinks.Unlock()
+	accountLinks.load()
+	for i, link := range accountLinks.data {
+		if link.UserID == userID {
+			accountLinks.data = append(accountLinks.data[:i], accountLinks.data[i+1:]...)
+			return link.Player, accountLinks.save()
+		}
+	}
+	return "", nil
+}
+
+// AccountLinks returns all links
+func AccountLinks() []AccountLinkT {
+	accountLinks.Lock()
+	defer accountLinks.Unlock()
+	accountLinks.load()
+	return append([]AccountLinkT(nil), accountLinks.data...)
+}
+
+// LinkedPlayer returns the player linked with the user, empty if there's none
+func LinkedPlayer(userID string) string {
+	accountLinks.Lock()
+	defer accountLinks.Unlock()
+	accountLinks.load()
+	for _, link := range accountLinks.data {
+		if link.UserID == userID {
+			return link.Player
+		}
+	}
+	return ""
+}
+
+// LinkedUser returns the id of the user linked with the player, empty if there's none
+func LinkedUser(player string) string {
+	accountLinks.Lock()
+	defer accountLinks.Unlock()
+	accountLinks.load()
+	for _, link := range accountLinks.data {
+		if strings.EqualFold(link.Player, player) {
+			return link.UserID
+		}
+	}
+	return ""
+}
@@ -0,0 +1,124 @@
+package support
+
+import (
+	"strings"
+	"time"
+)
+
+// Kinds of muted bridge users
+const (
+	MuteDiscord = "discord"
+	MutePlayer  = "player"
+)
+
+// BridgeMuteT stops messages of a discord user or a player from crossing the bridge
+type BridgeMuteT struct {
+	Kind string `json:"kind"`
+	// discord user id or player name
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// zero - until unmuted
+	Until    time.Time `json:"until,omitempty"`
+	By       string    `json:"by"`
+	Notified bool      `json:"notified"`
+}
+
+// Expired tells if the mute is over
+func (mute *BridgeMuteT) Expired() bool {
+	return !mute.Until.IsZero() && time.Now().After(mute.Until)
+}
+
+func (mute *BridgeMuteT) matches(kind, id string) bool {
+	if mute.Kind != kind {
+		return false
+	}
+	if kind == MutePlayer {
+		return strings.EqualFold(mute.ID, id)
+	}
+	return mute.ID == id
+}
+
+var bridgeMutes = jsonStoreT[[]BridgeMuteT]{filename: func() string { return Config.BridgeMutesFile }}
+
+// saveBridgeMutes drops expired mutes and writes the rest. bridgeMutes has to be locked
+func saveBridgeMutes() error {
+	list := bridgeMutes.data[:0]
+	for _, mute := range bridgeMutes.data {
+		if !mute.Expired() {
+			list = append(list, mute)
+		}
+	}
+	bridgeMutes.data = list
+	return bridgeMutes.save()
+}
+
+// MuteBridge adds the mute or replaces the previous mute of the same user
+func MuteBridge(mute BridgeMuteT) error {
+	bridgeMutes.Lock()
+	defer bridgeMutes.Unlock()
+	bridgeMutes.load()
+	for i := range bridgeMutes.data {
+		if bridgeMutes.data[i].matches(mute.Kind, mute.ID) {
+			bridgeMutes.data[i] = mute
+			return saveBridgeMutes()
+		}
+	}
+	bridgeMutes.data = append(bridgeMutes.data, mute)
+	return saveBridgeMutes()
+}
+
+// UnmuteBridge removes the mute, false if the user wasn't muted
+func UnmuteBridge(kind, id string) (bool, error) {
+	bridgeMutes.Lock()
+	defer bridgeMutes.Unlock()
+	bridgeMutes.load()
+	for i := range bridgeMutes.data {
+		if bridgeMutes.data[i].matches(kind, id) {
+			expired := bridgeMutes.data[i].Expired()
+			bridgeMutes.data = append(bridgeMutes.data[:i], bridgeMutes.data[i+1:]...)
+			return !expired, saveBridgeMutes()
+		}
+	}
+	return false, nil
+}
+
+// BridgeMutes returns the active mutes
+func BridgeMutes() []BridgeMuteT {
+	bridgeMutes.Lock()
+	defer bridgeMutes.Unlock()
+	bridgeMutes.load()
+	var res []BridgeMuteT
+	for _, mute := range bridgeMutes.data {
+		if !mute.Expired() {
+			res = append(res, mute)
+		}
+	}
+	return res
+}
+
+// BridgeMuted returns the active mute of the user. notify is true the first time it's asked after the user was muted
+func BridgeMuted(kind, id string) (mute *BridgeMuteT, notify bool) {
+	bridgeMutes.Lock()
+	defer bridgeMutes.Unlock()
+	bridgeMutes.load()
+	for i := range bridgeMutes.data {
+		if !bridgeMutes.data[i].matches(kind, id) || bridgeMutes.data[i].Expired() {
+			continue
+		}
+		res := bridgeMutes.data[i]
+		if !res.Notified {
+			bridgeMutes.data[i].Notified = true
+			Panik(saveBridgeMutes(), "... when writing "+Config.BridgeMutesFile)
+		}
+		return &res, !res.Notified
+	}
+	return nil, false
+}
+
+// Describe returns "until <time>" or "until unmuted"
+func (mute *BridgeMuteT) Describe() string {
+	if mute.Until.IsZero() {
+		return "until unmuted"
+	}
+	return "until " + mute.Until.Format("2006-01-02 15:04")
+}
@@ -1,5 +1,34 @@
 package support
 
-import "regexp"
+import (
+	"regexp"
+	"strconv"
+	"time"
+)
 
 var ModFileRegexp = regexp.MustCompile(`([A-Za-z0-9_\- ]+)_(\d+\.\d+\.\d+)(\.zip)?`)
+
+var userMentionRegexp = regexp.MustCompile(`^(?:<@!?(\d+)>|(\d{17,20}))$`)
+
+// ParseUserMention returns the user id from a mention (<@id> or <@!id>) or a bare discord id
+func ParseUserMention(s string) (string, bool) {
+	match := userMentionRegexp.FindStringSubmatch(s)
+	if match == nil {
+		return "", false
+	}
+	return match[1] + match[2], true
+}
+
+var daysRegexp = regexp.MustCompile(`^(\d+)d$`)
+
+// ParseDuration is time.ParseDuration that also accepts days, e.g. `7d`
+func ParseDuration(s string) (time.Duration, error) {
+	if match := daysRegexp.FindStringSubmatch(s); match != nil {
+		days, err := strconv.Atoi(match[1])
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
@@ -0,0 +1,106 @@
+package support
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Discord markdown, in the order it's translated
+var markdownRules = []struct {
+	regexp  *regexp.Regexp
+	replace string
+}{
+	{regexp.MustCompile("```(?:[\\w+-]*\n)?([\\s\\S]*?)\n?```"), "$1"},
+	{regexp.MustCompile("`([^`\n]+)`"), "$1"},
+	{regexp.MustCompile(`(?s)\|\|.+?\|\|`), "(spoiler)"},
+	{regexp.MustCompile(`\[([^\]\n]+)]\(<?(https?://[^)\s>]+)>?\)`), "$1 ($2)"},
+	{regexp.MustCompile(`<(https?://[^>\s]+)>`), "$1"},
+	{regexp.MustCompile(`(?s)\*\*(.+?)\*\*`), "$1"},
+	{regexp.MustCompile(`(?s)__(.+?)__`), "$1"},
+	{regexp.MustCompile(`(?s)~~(.+?)~~`), "$1"},
+	{regexp.MustCompile(`\*([^*\s][^*\n]*?)\*`), "$1"},
+	{regexp.MustCompile(`(^|\W)_([^_\s][^_\n]*?)_(\W|$)`), "$1$2$3"},
+	{regexp.MustCompile(`(?m)^(?:#{1,3}|-#) `), ""},
+}
+
+// escaped markdown characters are hidden from markdownRules as private use characters
+var (
+	hideEscaped = strings.NewReplacer(`\*`, "\uE000", `\_`, "\uE001", `\~`, "\uE002", `\|`, "\uE003", "\\`", "\uE004", `\\`, "\uE005")
+	showEscaped = strings.NewReplacer("\uE000", "*", "\uE001", "_", "\uE002", "~", "\uE003", "|", "\uE004", "`", "\uE005", `\`)
+)
+
+// tags that change how the game chat looks, users can't send them
+var styleTagRegexp = regexp.MustCompile(`(?i)\[(/?(?:color|font))\b`)
+
+// EscapeRichText stops the game from interpreting [color] and [font] tags in the text
+func EscapeRichText(text string) string {
+	return styleTagRegexp.ReplaceAllString(text, "[\u200b$1")
+}
+
+// MarkdownToRichText translates a discord message into text for the game chat
+func MarkdownToRichText(text string) string {
+	text = hideEscaped.Replace(text)
+	for _, rule := range markdownRules {
+		text = rule.regexp.ReplaceAllString(text, rule.replace)
+	}
+	// escaped last, so markdown can't put a tag back together
+	return EscapeRichText(showEscaped.Replace(text))
+}
+
+var richTextTagRegexp = regexp.MustCompile(`\[([a-z][a-z-]*)=([^\]]*)]|\[/(?:color|font)]`)
+
+// ReadableName turns an internal name (iron-gear-wheel) into a readable one (Iron gear wheel)
+func ReadableName(name string) string {
+	name = strings.ReplaceAll(name, "-", " ")
+	if name == "" {
+		return name
+	}
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+func richTextTag(tag, value string) string {
+	switch tag {
+	case "color", "font":
+		return ""
+	case "item", "entity", "fluid", "recipe", "technology", "virtual-signal", "tile", "item-group",
+		"achievement", "equipment", "planet", "space-location", "quality", "asteroid-chunk":
+		name, _ := SplitDivide(value, ",")
+		return ReadableName(name)
+	case "img":
+		i := strings.LastIndex(value, "/")
+		return ReadableName(value[i+1:])
+	case "gps":
+		parts := strings.Split(value, ",")
+		if len(parts) < 2 {
+			break
+		}
+		surface := "nauvis"
+		if len(parts) > 2 && parts[2] != "" {
+			surface = parts[2]
+		}
+		return fmt.Sprintf("%s, %s on %s", parts[0], parts[1], surface)
+	case "train":
+		return "train " + value
+	case "train-stop":
+		return "train stop " + value
+	case "space-platform":
+		return "space platform " + value
+	case "armor":
+		return value + "'s armor"
+	case "special-item":
+		return "blueprint"
+	}
+	return "[" + tag + "=" + value + "]"
+}
+
+// RichTextToMarkdown translates text from the game chat into a discord message
+func RichTextToMarkdown(text string) string {
+	return richTextTagRegexp.ReplaceAllStringFunc(text, func(match string) string {
+		groups := richTextTagRegexp.FindStringSubmatch(match)
+		if groups[1] == "" {
+			return "" // closing tag
+		}
+		return richTextTag(groups[1], groups[2])
+	})
+}
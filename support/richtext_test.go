@@ -0,0 +1,40 @@
+package support
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMarkdownToRichTextEscapesTags(t *testing.T) {
+	for _, input := range []string{
+		"[color=red]hi[/color]",
+		"[**color**=red]hi",
+		"[`color`=red]hi",
+		"[__font__=default-large-bold]hi",
+		"[*font*=default-bold]hi[/**font**]",
+		"[\\color=red]hi",
+	} {
+		output := MarkdownToRichText(input)
+		lower := strings.ToLower(output)
+		for _, tag := range []string{"[color", "[/color", "[font", "[/font"} {
+			if strings.Contains(lower, tag) {
+				t.Errorf("MarkdownToRichText(%q) = %q contains %s", input, output, tag)
+			}
+		}
+	}
+}
+
+func TestMarkdownToRichText(t *testing.T) {
+	for input, expected := range map[string]string{
+		"**bold** and *italic*": "bold and italic",
+		"snake_case_name":       "snake_case_name",
+		"||secret||":            "(spoiler)",
+		"[x](https://a.b/c)":    "x (https://a.b/c)",
+		"```go\ncode\n```":      "code",
+		"\\*not italic\\*":      "*not italic*",
+	} {
+		if output := MarkdownToRichText(input); output != expected {
+			t.Errorf("MarkdownToRichText(%q) = %q, expected %q", input, output, expected)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package support
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// the permission group players are put in when they lose all roles with groups
+const defaultPermissionGroup = "Default"
+
+// RoleSyncRecordT is what role_sync applied to a player
+type RoleSyncRecordT struct {
+	Player string `json:"player"`
+	Admin  bool   `json:"admin"`
+	Group  string `json:"group"`
+}
+
+func (r *RoleSyncRecordT) privileged() bool {
+	return r.Admin || (r.Group != "" && r.Group != defaultPermissionGroup)
+}
+
+// roleSynced remembers the players role_sync promoted or put in a group, so they are reset when they lose the link
+var roleSynced = jsonStoreT[map[string]RoleSyncRecordT]{
+	data:     map[string]RoleSyncRecordT{},
+	filename: func() string { return Config.RoleSyncFile },
+}
+
+// linkedRoles returns the roles of the user. A user who isn't a member has no roles,
+// false means the roles are unknown and nothing should change
+func linkedRoles(s *discordgo.Session, userID string) ([]string, bool) {
+	member, err := s.GuildMember(GuildID, userID)
+	if err == nil {
+		return member.Roles, true
+	}
+	var restErr *discordgo.RESTError
+	if errors.As(err, &restErr) && restErr.Message != nil && restErr.Message.Code == discordgo.ErrCodeUnknownMember {
+		return nil, true
+	}
+	Panik(err, "... when requesting the member for role_sync")
+	if member, err := s.State.Member(GuildID, userID); err == nil {
+		return member.Roles, true
+	}
+	return nil, false
+}
+
+// rolePrivileges returns what the roles give according to role_sync
+func rolePrivileges(player string, roles []string) RoleSyncRecordT {
+	privileges := RoleSyncRecordT{Player: player, Group: defaultPermissionGroup}
+	groupSet := false
+	for _, rule := range Config.RoleSync {
+		if !containsRole(roles, rule.RoleID) {
+			continue
+		}
+		if rule.Admin {
+			privileges.Admin = true
+		}
+		if rule.Group != "" && !groupSet {
+			privileges.Group = rule.Group
+			groupSet = true
+		}
+	}
+	return privileges
+}
+
+func containsRole(roles []string, roleID string) bool {
+	for _, role := range roles {
+		if role == roleID {
+			return true
+		}
+	}
+	return false
+}
+
+// SyncPlayerRoles promotes or demotes the player and moves them to the permission group of their roles.
+// Players who lost the link or left the guild lose what role_sync gave them.
+// Unless force is set it does nothing when the privileges didn't change since the last time
+func SyncPlayerRoles(s *discordgo.Session, player string, force bool) {
+	if player == "" {
+		return
+	}
+	key := strings.ToLower(player)
+	roleSynced.Lock()
+	roleSynced.load()
+	last, recorded := roleSynced.data[key]
+	roleSynced.Unlock()
+
+	var privileges RoleSyncRecordT
+	if userID := LinkedUser(player); userID != "" {
+		if len(Config.RoleSync) == 0 && !recorded {
+			return
+		}
+		roles, ok := linkedRoles(s, userID)
+		if !ok {
+			return
+		}
+		privileges = rolePrivileges(player, roles)
+	} else if recorded {
+		privileges = RoleSyncRecordT{Player: player, Group: defaultPermissionGroup}
+	} else {
+		// role_sync never touched the player
+		return
+	}
+	if !recorded {
+		last = RoleSyncRecordT{Player: player, Group: defaultPermissionGroup}
+	}
+	if last == privileges && !force {
+		return
+	}
+
+	// players are demoted or reset only if role_sync gave them the rights,
+	// admins from the server adminlist and groups set by hand stay untouched
+	var commands []string
+	if privileges.Admin && (force || !last.Admin) {
+		commands = append(commands, "/promote "+player)
+	} else if !privileges.Admin && recorded && last.Admin {
+		commands = append(commands, "/demote "+player)
+	}
+	if privileges.Group != defaultPermissionGroup && (force || privileges.Group != last.Group) {
+		commands = append(commands, "/permissions add-player "+privileges.Group+" "+player)
+	} else if privileges.Group == defaultPermissionGroup && recorded && last.Group != defaultPermissionGroup {
+		commands = append(commands, "/permissions add-player "+defaultPermissionGroup+" "+player)
+	}
+	sent := true
+	for _, command := range commands {
+		if sent = Factorio.Send(command); !sent {
+			break
+		}
+	}
+	if !sent {
+		return
+	}
+	roleSynced.Lock()
+	defer roleSynced.Unlock()
+	if privileges.privileged() {
+		roleSynced.data[key] = privileges
+	} else {
+		delete(roleSynced.data, key)
+	}
+	Panik(roleSynced.save(), "... when writing "+Config.RoleSyncFile)
+}
+
+// SyncRoles applies role_sync to all linked players and to the players it promoted before
+func SyncRoles(s *discordgo.Session) {
+	players := map[string]string{}
+	for _, link := range AccountLinks() {
+		players[strings.ToLower(link.Player)] = link.Player
+	}
+	roleSynced.Lock()
+	roleSynced.load()
+	for key, record := range roleSynced.data {
+		players[key] = record.Player
+	}
+	roleSynced.Unlock()
+	for _, player := range players {
+		SyncPlayerRoles(s, player, false)
+	}
+}
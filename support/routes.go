@@ -0,0 +1,101 @@
+package support
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Types of messages sent from the game to discord
+const (
+	RouteChat    = "CHAT"
+	RouteJoin    = "JOIN"
+	RouteLeave   = "LEAVE"
+	RouteKick    = "KICK"
+	RouteBan     = "BAN"
+	RouteDiscord = "DISCORD" // messages printed by mods, e.g. control.lua
+	RouteDeath   = "DEATH"
+	RouteSave    = "SAVE"
+	RouteServer  = "SERVER" // the server has started, stopped or crashed
+)
+
+// RouteT connects a discord channel with the game
+type RouteT struct {
+	ChannelID string `json:"channel_id"`
+	// "in" - from discord to the game, "out" - from the game to discord, "both"
+	Direction string `json:"direction"`
+	// types of messages sent to discord, empty - all types
+	Types  []string `json:"types"`
+	Prefix string   `json:"prefix"`
+}
+
+func (r *RouteT) out() bool {
+	return r.Direction == "out" || r.Direction == "both" || r.Direction == ""
+}
+
+func (r *RouteT) in() bool {
+	return r.Direction == "in" || r.Direction == "both" || r.Direction == ""
+}
+
+func (r *RouteT) accepts(messageType string) bool {
+	if len(r.Types) == 0 {
+		return true
+	}
+	for _, t := range r.Types {
+		if strings.EqualFold(t, messageType) {
+			return true
+		}
+	}
+	return false
+}
+
+// Routes returns all routes. factorio_channel_id is bridged in both directions unless it has its own route
+func Routes() []RouteT {
+	for _, route := range Config.Routes {
+		if route.ChannelID == Config.FactorioChannelID {
+			return Config.Routes
+		}
+	}
+	return append([]RouteT{{ChannelID: Config.FactorioChannelID, Direction: "both"}}, Config.Routes...)
+}
+
+// InboundRoute returns the route that bridges messages from the channel to the game
+func InboundRoute(channelID string) *RouteT {
+	for _, route := range Routes() {
+		if route.ChannelID == channelID && route.in() {
+			return &route
+		}
+	}
+	return nil
+}
+
+// SendRouted sends a message from the game to every channel that accepts its type.
+// It returns the message sent to factorio_channel_id
+func SendRouted(s *discordgo.Session, messageType string, message string) *MessageControlT {
+	if message == "" {
+		return nil
+	}
+	return SendRoutedComplex(s, messageType, &discordgo.MessageSend{Content: message})
+}
+
+// SendRoutedComplex is SendRouted for messages with embeds
+func SendRoutedComplex(s *discordgo.Session, messageType string, message *discordgo.MessageSend) *MessageControlT {
+	var res *MessageControlT
+	for _, route := range Routes() {
+		if !route.out() || !route.accepts(messageType) {
+			continue
+		}
+		routed := *message
+		if route.Prefix != "" {
+			routed.Content = route.Prefix + " " + routed.Content
+		}
+		if route.ChannelID == Config.FactorioChannelID {
+			res = SendComplex(s, &routed)
+			continue
+		}
+		_, err := s.ChannelMessageSendComplex(route.ChannelID, &routed)
+		Panik(err, fmt.Sprintf("Failed to send a message to %s: %+v", route.ChannelID, routed))
+	}
+	return res
+}
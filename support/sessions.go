@@ -0,0 +1,235 @@
+package support
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PlayerSessionT is the time a player spent on the server
+type PlayerSessionT struct {
+	Player string    `json:"player"`
+	Joined time.Time `json:"joined"`
+	// zero while the player is online
+	Left time.Time `json:"left,omitempty"`
+	// the last heartbeat while the session was open
+	Seen time.Time `json:"seen,omitempty"`
+	// the bot didn't see the player leave, Left is the last heartbeat
+	Estimated bool `json:"estimated,omitempty"`
+}
+
+// PlayerStatsT sums up the sessions of a player
+type PlayerStatsT struct {
+	Player    string
+	FirstSeen time.Time
+	LastSeen  time.Time
+	Online    bool
+	Sessions  int
+	Playtime  time.Duration
+	// some sessions ended while the bot wasn't watching
+	Estimated bool
+}
+
+var playerSessions = jsonStoreT[[]PlayerSessionT]{filename: func() string { return Config.SessionsFile }}
+
+const sessionHeartbeat = time.Minute
+
+var heartbeatOnce sync.Once
+
+// closeSessions ends the open sessions of the player, or of everyone if player is empty. playerSessions has to be locked
+func closeSessions(player string, t time.Time) (time.Duration, bool) {
+	var duration time.Duration
+	closed := false
+	for i := range playerSessions.data {
+		session := &playerSessions.data[i]
+		if !session.Left.IsZero() || (player != "" && !strings.EqualFold(session.Player, player)) {
+			continue
+		}
+		session.Left = t
+		if session.Left.Before(session.Joined) {
+			session.Left = session.Joined
+		}
+		duration = session.Left.Sub(session.Joined)
+		closed = true
+	}
+	return duration, closed
+}
+
+// closeStaleSessions ends the open sessions of the player, or of everyone if player is empty, whose end the bot missed.
+// They end at their last heartbeat and are marked as estimated, the real time is unknown. playerSessions has to be locked
+func closeStaleSessions(player string) bool {
+	closed := false
+	for i := range playerSessions.data {
+		session := &playerSessions.data[i]
+		if !session.Left.IsZero() || (player != "" && !strings.EqualFold(session.Player, player)) {
+			continue
+		}
+		session.Left = session.Seen
+		if session.Left.Before(session.Joined) {
+			session.Left = session.Joined
+		}
+		session.Estimated = true
+		closed = true
+	}
+	return closed
+}
+
+// StartSession records that the player joined. A session that is still open missed the leave
+func StartSession(player string) {
+	playerSessions.Lock()
+	defer playerSessions.Unlock()
+	playerSessions.load()
+	now := time.Now()
+	closeStaleSessions(player)
+	playerSessions.data = append(playerSessions.data, PlayerSessionT{Player: player, Joined: now, Seen: now})
+	Panik(playerSessions.save(), "... when writing "+Config.SessionsFile)
+	heartbeatOnce.Do(func() {
+		go heartbeatSessions()
+	})
+}
+
+// heartbeatSessions records every minute that the open sessions are still going,
+// so that sessions left open by a crash of the bot end close to the real time
+func heartbeatSessions() {
+	for range time.Tick(sessionHeartbeat) {
+		if !Factorio.IsRunning() {
+			continue
+		}
+		playerSessions.Lock()
+		now := time.Now()
+		changed := false
+		for i := range playerSessions.data {
+			if playerSessions.data[i].Left.IsZero() {
+				playerSessions.data[i].Seen = now
+				changed = true
+			}
+		}
+		if changed {
+			Panik(playerSessions.save(), "... when writing "+Config.SessionsFile)
+		}
+		playerSessions.Unlock()
+	}
+}
+
+// EndSession records that the player left and returns how long they played, false if they weren't online
+func EndSession(player string) (time.Duration, bool) {
+	playerSessions.Lock()
+	defer playerSessions.Unlock()
+	playerSessions.load()
+	duration, ok := closeSessions(player, time.Now())
+	if ok {
+		Panik(playerSessions.save(), "... when writing "+Config.SessionsFile)
+	}
+	return duration, ok
+}
+
+// CloseAllSessions ends every open session when the server stops
+func CloseAllSessions() {
+	playerSessions.Lock()
+	defer playerSessions.Unlock()
+	playerSessions.load()
+	if _, ok := closeSessions("", time.Now()); ok {
+		Panik(playerSessions.save(), "... when writing "+Config.SessionsFile)
+	}
+}
+
+// CloseStaleSessions ends sessions left open when the bot didn't see the server stop
+func CloseStaleSessions() {
+	playerSessions.Lock()
+	defer playerSessions.Unlock()
+	playerSessions.load()
+	if closeStaleSessions("") {
+		Panik(playerSessions.save(), "... when writing "+Config.SessionsFile)
+	}
+}
+
+// SessionStats returns the statistics of the player, false if the player was never seen
+func SessionStats(player string) (PlayerStatsT, bool) {
+	playerSessions.Lock()
+	defer playerSessions.Unlock()
+	playerSessions.load()
+	stats := PlayerStatsT{}
+	now := time.Now()
+	for _, session := range playerSessions.data {
+		if !strings.EqualFold(session.Player, player) {
+			continue
+		}
+		if stats.Sessions == 0 {
+			stats.Player = session.Player
+			stats.FirstSeen = session.Joined
+		}
+		stats.Sessions++
+		left := session.Left
+		if left.IsZero() {
+			stats.Online = true
+			left = now
+		}
+		stats.Playtime += left.Sub(session.Joined)
+		stats.Estimated = stats.Estimated || session.Estimated
+		if left.After(stats.LastSeen) {
+			stats.LastSeen = left
+		}
+	}
+	return stats, stats.Sessions > 0
+}
+
+// PlaytimeT is a line of the playtime leaderboard
+type PlaytimeT struct {
+	Player   string
+	Playtime time.Duration
+}
+
+// Playtimes returns the time players spent on the server since the time, the longest first
+func Playtimes(since time.Time) []PlaytimeT {
+	playerSessions.Lock()
+	defer playerSessions.Unlock()
+	playerSessions.load()
+	now := time.Now()
+	index := map[string]int{}
+	var res []PlaytimeT
+	for _, session := range playerSessions.data {
+		joined, left := session.Joined, session.Left
+		if left.IsZero() {
+			left = now
+		}
+		if joined.Before(since) {
+			joined = since
+		}
+		if !left.After(joined) {
+			continue
+		}
+		key := strings.ToLower(session.Player)
+		i, ok := index[key]
+		if !ok {
+			i = len(res)
+			index[key] = i
+			res = append(res, PlaytimeT{Player: session.Player})
+		}
+		res[i].Playtime += left.Sub(joined)
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].Playtime > res[j].Playtime
+	})
+	return res
+}
+
+// FormatDuration formats a duration in a human-readable format (e.g., "2h 30m 15s")
+func FormatDuration(d time.Duration) string {
+	totalSec := int(d.Seconds())
+	hours := totalSec / 3600
+	minutes := (totalSec % 3600) / 60
+	seconds := totalSec % 60
+
+	var parts []string
+	if hours > 0 {
+		parts = append(parts, fmt.Sprintf("%dh", hours))
+	}
+	if minutes > 0 {
+		parts = append(parts, fmt.Sprintf("%dm", minutes))
+	}
+	parts = append(parts, fmt.Sprintf("%ds", seconds))
+
+	return strings.Join(parts, " ")
+}
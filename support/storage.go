@@ -0,0 +1,71 @@
+package support
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// ReadJSON reads a json file into v. If the file doesn't exist v is left untouched
+func ReadJSON(filename string, v interface{}) error {
+	contents, err := os.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(contents, v)
+}
+
+// WriteJSON writes v into a json file. The file is replaced atomically so it is never left half-written
+func WriteJSON(filename string, v interface{}) error {
+	contents, err := json.MarshalIndent(v, "", "    ")
+	if err != nil {
+		return err
+	}
+	file, err := os.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+"-*")
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(contents)
+	closeErr := file.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(file.Name(), 0664)
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), filename)
+	}
+	if err != nil {
+		_ = os.Remove(file.Name())
+	}
+	return err
+}
+
+// jsonStoreT is a json file that is read on the first use and then kept in memory
+type jsonStoreT[T any] struct {
+	sync.Mutex
+	loaded bool
+	data   T
+	// the name is taken from the config every time, so $config load can change it
+	filename func() string
+}
+
+// load reads the file once. The store has to be locked
+func (st *jsonStoreT[T]) load() {
+	if st.loaded {
+		return
+	}
+	filename := st.filename()
+	Panik(ReadJSON(filename, &st.data), "... when reading "+filename)
+	st.loaded = true
+}
+
+// save writes the data to the file. The store has to be locked
+func (st *jsonStoreT[T]) save() error {
+	return WriteJSON(st.filename(), st.data)
+}
@@ -2,7 +2,9 @@ package support
 
 import (
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -239,6 +241,39 @@ func DirExists(filename string) bool {
 	return info.IsDir()
 }
 
+// MoveFile renames a file falling back to copying it if src and dst are on different filesystems
+func MoveFile(src, dst string) error {
+	err := os.Rename(src, dst)
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(*os.LinkError); !ok || !FileExists(src) {
+		return err
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.CreateTemp(filepath.Dir(dst), ".move-*")
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	closeErr := out.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(out.Name(), dst)
+	}
+	if err != nil {
+		_ = os.Remove(out.Name())
+		return err
+	}
+	return os.Remove(src)
+}
+
 func PluralS(x int) string {
 	if x > 1 {
 		return "s"
@@ -246,6 +281,20 @@ func PluralS(x int) string {
 	return ""
 }
 
+// FormatSize formats a number of bytes in a human-readable format (e.g. "1.5 MiB")
+func FormatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
 type WriteCounter struct {
 	Total       uint64
 	Transferred uint64
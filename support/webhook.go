@@ -0,0 +1,139 @@
+package support
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// webhooks caches the webhook of every channel that receives chat through a webhook
+var webhooks = struct {
+	sync.Mutex
+	channels map[string]*discordgo.Webhook
+}{channels: map[string]*discordgo.Webhook{}}
+
+// PlayerAvatar returns the avatar of the discord user linked with the player or the generated avatar
+func PlayerAvatar(s *discordgo.Session, username string) string {
+	if userID := LinkedUser(username); userID != "" {
+		if member, err := s.State.Member(GuildID, userID); err == nil {
+			return member.AvatarURL("128")
+		}
+	}
+	return GeneratedAvatar(username)
+}
+
+// GeneratedAvatar returns webhook.avatar_url for the player
+func GeneratedAvatar(username string) string {
+	return strings.ReplaceAll(Config.Webhook.AvatarURL, "{username}", url.QueryEscape(username))
+}
+
+// discord rejects webhook usernames containing these words
+var webhookUsernameRegexp = regexp.MustCompile(`(?i)discord|clyde`)
+
+func channelWebhook(s *discordgo.Session, channelID string) (*discordgo.Webhook, error) {
+	webhooks.Lock()
+	defer webhooks.Unlock()
+	if webhook, ok := webhooks.channels[channelID]; ok {
+		return webhook, nil
+	}
+	existing, err := s.ChannelWebhooks(channelID)
+	if err != nil {
+		return nil, err
+	}
+	for _, webhook := range existing {
+		if webhook.Name == Config.Webhook.Name && webhook.Token != "" && webhook.User != nil && webhook.User.ID == s.State.User.ID {
+			webhooks.channels[channelID] = webhook
+			return webhook, nil
+		}
+	}
+	webhook, err := s.WebhookCreate(channelID, Config.Webhook.Name, "")
+	if err != nil {
+		return nil, err
+	}
+	webhooks.channels[channelID] = webhook
+	return webhook, nil
+}
+
+// IsOwnWebhook tells if the message was sent by a webhook of the bot
+func IsOwnWebhook(webhookID string) bool {
+	if webhookID == "" {
+		return false
+	}
+	webhooks.Lock()
+	defer webhooks.Unlock()
+	for _, webhook := range webhooks.channels {
+		if webhook.ID == webhookID {
+			return true
+		}
+	}
+	return false
+}
+
+// sendAsPlayer posts the message through the channel webhook with the player's name and avatar
+func sendAsPlayer(s *discordgo.Session, channelID, username, message string) error {
+	webhook, err := channelWebhook(s, channelID)
+	if err != nil {
+		return err
+	}
+	avatar := PlayerAvatar(s, username)
+	if webhookUsernameRegexp.MatchString(username) {
+		username = webhookUsernameRegexp.ReplaceAllString(username, "***")
+	}
+	mentions := &discordgo.MessageAllowedMentions{
+		Parse: []discordgo.AllowedMentionType{discordgo.AllowedMentionTypeUsers},
+	}
+	if Config.AllowPingingEveryone {
+		mentions.Parse = append(mentions.Parse, discordgo.AllowedMentionTypeEveryone)
+	}
+	_, err = s.WebhookExecute(webhook.ID, webhook.Token, false, &discordgo.WebhookParams{
+		Content:         message,
+		Username:        username,
+		AvatarURL:       avatar,
+		AllowedMentions: mentions,
+	})
+	if err != nil {
+		// the webhook could have been deleted, it'll be created again next time
+		webhooks.Lock()
+		delete(webhooks.channels, channelID)
+		webhooks.Unlock()
+	}
+	return err
+}
+
+// SendRoutedAs sends a chat message of a player. With webhook.enabled it's posted under the player's name,
+// otherwise (or if the webhook fails) fallback is sent by the bot
+func SendRoutedAs(s *discordgo.Session, messageType, username, message, fallback string) {
+	if !Config.Webhook.Enabled {
+		SendRouted(s, messageType, fallback)
+		return
+	}
+	for _, route := range Routes() {
+		if !route.out() || !route.accepts(messageType) {
+			continue
+		}
+		routed := message
+		if route.Prefix != "" {
+			routed = route.Prefix + " " + routed
+		}
+		err := sendAsPlayer(s, route.ChannelID, username, routed)
+		if err != nil {
+			Panik(err, "... when sending a message through the webhook")
+			routed = fallback
+			if route.Prefix != "" {
+				routed = route.Prefix + " " + routed
+			}
+			if route.ChannelID == Config.FactorioChannelID {
+				SendComplex(s, &discordgo.MessageSend{Content: routed})
+			} else {
+				SendTo(s, routed, route.ChannelID)
+			}
+			continue
+		}
+		if route.ChannelID == Config.FactorioChannelID {
+			MyLastMessage = false
+		}
+	}
+}